@@ -0,0 +1,290 @@
+// Command keyctl manages the JWT keyring directory read by the server when
+// JWT_KEYS_DIR is set.
+//
+// A zero-downtime rotation is:
+//
+//	keyctl generate -alg ES256          # published in JWKS, not yet signing
+//	keyctl promote <kid> -retire-after 48h
+//	keyctl prune                        # after the old key has retired
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"go-auth/pkg/utils"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch command {
+	case "list":
+		err = list(args)
+	case "generate":
+		err = generate(args)
+	case "import":
+		err = importKey(args)
+	case "promote":
+		err = promote(args)
+	case "retire":
+		err = retire(args)
+	case "prune":
+		err = prune(args)
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "keyctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: keyctl <command> [flags]
+
+commands:
+  list                          show the keys in the keyring
+  generate [-alg ES256]         create a verification key, published before it signs
+  import -kid K -alg A -file F  add an existing key or HMAC secret file
+  promote <kid>                 make kid the active signing key
+  retire <kid>                  stop accepting tokens signed with kid
+  prune                         delete retired keys
+
+every command accepts -dir (default $JWT_KEYS_DIR)`)
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	dir := fs.String("dir", os.Getenv("JWT_KEYS_DIR"), "keyring directory")
+	return fs, dir
+}
+
+// parseWithKID parses flags that may appear before or after the kid argument
+func parseWithKID(fs *flag.FlagSet, args []string) (string, error) {
+	var kid string
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		kid, args = args[0], args[1:]
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+
+	if kid == "" {
+		kid = fs.Arg(0)
+	}
+	if kid == "" {
+		return "", fmt.Errorf("%s requires a kid", fs.Name())
+	}
+
+	return kid, nil
+}
+
+func loadManifest(dir string) (*utils.KeyringManifest, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("keyring directory is required (-dir or JWT_KEYS_DIR)")
+	}
+	return utils.LoadKeyringManifest(dir)
+}
+
+func list(args []string) error {
+	fs, dir := newFlagSet("list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	manifest, err := loadManifest(*dir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tALG\tSTATUS\tCREATED\tRETIRE AT")
+	for _, entry := range manifest.Keys {
+		retireAt := "-"
+		if entry.RetireAt != nil {
+			retireAt = entry.RetireAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.KID, entry.Algorithm, entry.Status,
+			entry.CreatedAt.Format(time.RFC3339), retireAt)
+	}
+
+	return w.Flush()
+}
+
+func generate(args []string) error {
+	fs, dir := newFlagSet("generate")
+	algorithm := fs.String("alg", "ES256", "HS256, RS256, ES256 or EdDSA")
+	activate := fs.Bool("activate", false, "make the key active straight away; only safe for the first key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	manifest, err := loadManifest(*dir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create keyring directory: %w", err)
+	}
+
+	entry, err := manifest.GenerateKeyringKey(*dir, *algorithm)
+	if err != nil {
+		return err
+	}
+
+	if *activate || len(manifest.Keys) == 1 {
+		if err := manifest.Promote(entry.KID, 0); err != nil {
+			return err
+		}
+	}
+
+	if err := manifest.Save(*dir); err != nil {
+		return err
+	}
+
+	fmt.Println(entry.KID)
+	return nil
+}
+
+func importKey(args []string) error {
+	fs, dir := newFlagSet("import")
+	kid := fs.String("kid", "", "key id, e.g. \"default\" for the current JWT_SECRET")
+	algorithm := fs.String("alg", "HS256", "HS256, RS256, ES256 or EdDSA")
+	file := fs.String("file", "", "PEM private key, or a file holding the HMAC secret")
+	activate := fs.Bool("activate", false, "make the imported key active")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *kid == "" || *file == "" {
+		return fmt.Errorf("import requires -kid and -file")
+	}
+
+	manifest, err := loadManifest(*dir)
+	if err != nil {
+		return err
+	}
+	if _, exists := manifest.Find(*kid); exists {
+		return fmt.Errorf("key %s already exists", *kid)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	if *algorithm != "HS256" {
+		if _, err := utils.ParseSigningKey(*kid, *algorithm, data); err != nil {
+			return fmt.Errorf("invalid %s key: %w", *algorithm, err)
+		}
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create keyring directory: %w", err)
+	}
+
+	name := *kid + ".pem"
+	if *algorithm == "HS256" {
+		name = *kid + ".secret"
+	}
+	if err := os.WriteFile(filepath.Join(*dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	manifest.Keys = append(manifest.Keys, utils.KeyringManifestEntry{
+		KID:       *kid,
+		Algorithm: *algorithm,
+		File:      name,
+		Status:    utils.KeyStatusVerify,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	if *activate || len(manifest.Keys) == 1 {
+		if err := manifest.Promote(*kid, 0); err != nil {
+			return err
+		}
+	}
+
+	return manifest.Save(*dir)
+}
+
+func promote(args []string) error {
+	fs, dir := newFlagSet("promote")
+	retireAfter := fs.Duration("retire-after", 48*time.Hour,
+		"how long the previous key keeps verifying; at least the access token lifetime")
+	kid, err := parseWithKID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := loadManifest(*dir)
+	if err != nil {
+		return err
+	}
+
+	if err := manifest.Promote(kid, *retireAfter); err != nil {
+		return err
+	}
+
+	return manifest.Save(*dir)
+}
+
+func retire(args []string) error {
+	fs, dir := newFlagSet("retire")
+	after := fs.Duration("after", 0, "retire once this much time has passed; immediately by default")
+	kid, err := parseWithKID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := loadManifest(*dir)
+	if err != nil {
+		return err
+	}
+
+	if err := manifest.Retire(kid, time.Now().Add(*after)); err != nil {
+		return err
+	}
+
+	return manifest.Save(*dir)
+}
+
+func prune(args []string) error {
+	fs, dir := newFlagSet("prune")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	manifest, err := loadManifest(*dir)
+	if err != nil {
+		return err
+	}
+
+	pruned := manifest.Prune(time.Now())
+	if err := manifest.Save(*dir); err != nil {
+		return err
+	}
+
+	for _, entry := range pruned {
+		if err := os.Remove(filepath.Join(*dir, entry.File)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete key %s: %w", entry.KID, err)
+		}
+		fmt.Println("pruned", entry.KID)
+	}
+
+	return nil
+}
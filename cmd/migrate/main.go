@@ -0,0 +1,217 @@
+// Command migrate applies and reverts the versioned SQL migrations embedded
+// in the server, using the same database settings as the server.
+//
+// A release that changes the schema is rolled out with:
+//
+//	migrate up        # before starting the new version with DB_AUTO_MIGRATE=false
+//	migrate status
+//
+// and rolled back with `migrate down` after stopping it.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/database"
+	"go-auth/pkg/utils"
+)
+
+const defaultMigrationsDir = "internal/database/migrations"
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch command {
+	case "up":
+		err = up(ctx, args)
+	case "down":
+		err = down(ctx, args)
+	case "status":
+		err = status(ctx, args)
+	case "create":
+		err = create(args)
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: migrate <command> [flags]
+
+commands:
+  up                   apply every pending migration
+  down [-steps 1]      revert the latest applied migrations
+  status               show each migration and when it was applied
+  create <name>        add an empty up/down pair to -dir (default `+defaultMigrationsDir+`)
+                       and to its sqlite subdirectory
+
+the database is configured like the server: DB_* variables or CONFIG_FILE`)
+}
+
+// connect opens the configured database with the migrations embedded in
+// this binary
+func connect() (*database.Migrator, error) {
+	utils.InitLogger()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	utils.ConfigureLogger(cfg.Log.Level, cfg.Log.Format)
+
+	if err := database.ConnectDatabase(cfg); err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := database.GetDB().DB()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := database.EmbeddedMigrations(cfg.Database.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return database.NewMigrator(sqlDB, cfg.Database.Driver, migrations)
+}
+
+func up(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("up", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	migrator, err := connect()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("no pending migrations")
+	}
+	return nil
+}
+
+func down(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("down", flag.ExitOnError)
+	steps := fs.Int("steps", 1, "number of migrations to revert")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *steps < 1 {
+		return fmt.Errorf("-steps must be at least 1")
+	}
+
+	migrator, err := connect()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	reverted, err := migrator.Down(ctx, *steps)
+	for _, migration := range reverted {
+		fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(reverted) == 0 {
+		fmt.Println("no applied migrations")
+	}
+	return nil
+}
+
+func status(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	migrator, err := connect()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
+
+func create(args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	dir := fs.String("dir", defaultMigrationsDir, "migrations directory")
+
+	// Accept the name before or after the flags
+	var name string
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		name, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if name == "" {
+		name = fs.Arg(0)
+	}
+	if name == "" {
+		return fmt.Errorf("create requires a name")
+	}
+
+	paths, err := database.CreateMigration(*dir, name)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+	return nil
+}
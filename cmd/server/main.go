@@ -1,18 +1,36 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"go-auth/internal/challenge"
 	"go-auth/internal/config"
 	"go-auth/internal/database"
 	"go-auth/internal/handlers"
+	"go-auth/internal/health"
+	"go-auth/internal/interfaces"
+	"go-auth/internal/metrics"
 	"go-auth/internal/middleware"
+	"go-auth/internal/models"
 	"go-auth/internal/repository"
+	"go-auth/internal/repository/memory"
+	redisstore "go-auth/internal/repository/redis"
+	"go-auth/internal/scheduler"
+	"go-auth/internal/server"
 	"go-auth/internal/services"
+	"go-auth/internal/sms"
+	"go-auth/internal/tracing"
+	"go-auth/pkg/ratelimit"
 	"go-auth/pkg/utils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
@@ -47,29 +65,60 @@ func main() {
 	if err != nil {
 		utils.Logger.WithError(err).Fatal("Failed to load config")
 	}
+	utils.ConfigureLogger(cfg.Log.Level, cfg.Log.Format)
+	utils.ConfigureRedaction(cfg.Log.RevealOTPCodes)
+	if cfg.Log.RevealOTPCodes {
+		utils.Logger.Warn("LOG_REVEAL_OTP_CODES is enabled: OTP codes are written to the log")
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), &cfg.Tracing, Version)
+	if err != nil {
+		utils.Logger.WithError(err).Fatal("Failed to initialize tracing")
+	}
 
 	if err := database.ConnectDatabase(cfg); err != nil {
 		utils.Logger.WithError(err).Fatal("Failed to connect to database")
 	}
 
-	if err := database.RunMigrations(); err != nil {
+	if cfg.Metrics.Enabled {
+		if err := metrics.InstrumentGORM(database.GetDB()); err != nil {
+			utils.Logger.WithError(err).Fatal("Failed to instrument database")
+		}
+	}
+
+	if cfg.Tracing.Enabled {
+		if err := tracing.InstrumentGORM(database.GetDB()); err != nil {
+			utils.Logger.WithError(err).Fatal("Failed to instrument database")
+		}
+	}
+
+	if err := database.RunMigrations(context.Background(), cfg.Database.AutoMigrate); err != nil {
 		utils.Logger.WithError(err).Fatal("Failed to run migrations")
 	}
 
+	// Applied after migrations, which may legitimately run for longer
+	if err := database.ApplyQueryTimeouts(database.GetDB(), &cfg.Database); err != nil {
+		utils.Logger.WithError(err).Fatal("Failed to configure query timeouts")
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		utils.Logger.WithError(err).Fatal("Failed to configure trusted proxies")
+	}
 
 	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.TracingMiddleware())
 	router.Use(middleware.LoggingMiddleware())
 	router.Use(middleware.RecoveryWithLogging())
 
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "API-Version"},
-		ExposeHeaders:    []string{"API-Version", "X-Request-ID"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
+		AllowOrigins:     cfg.CORS.AllowedOrigins,
+		AllowMethods:     cfg.CORS.AllowedMethods,
+		AllowHeaders:     cfg.CORS.AllowedHeaders,
+		ExposeHeaders:    cfg.CORS.ExposedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
 	}))
 
 	router.Use(middleware.APIVersionMiddleware())
@@ -85,60 +134,268 @@ func main() {
 		})
 	})
 
-	setupRoutes(router, cfg)
+	stopBackground := setupRoutes(router, cfg)
+
+	srv, err := server.New(&cfg.Server, ":"+cfg.Port, router)
+	if err != nil {
+		utils.Logger.WithError(err).Fatal("Failed to configure server")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if certs := srv.Certificates(); certs != nil {
+		go reloadCertificatesOnSIGHUP(ctx, certs)
+	}
 
 	utils.Logger.WithFields(map[string]interface{}{
 		"port":    cfg.Port,
+		"tls":     cfg.Server.TLSCertFile != "",
 		"version": Version,
 	}).Info("Server starting")
 
-	if err := router.Run(":" + cfg.Port); err != nil {
-		utils.Logger.WithError(err).Fatal("Failed to start server")
+	if err := srv.Run(ctx); err != nil {
+		utils.Logger.WithError(err).Error("Server stopped with error")
+	}
+
+	// In-flight requests have drained; stop the jobs before closing the pool
+	// they use
+	stopBackground()
+
+	if err := database.Close(); err != nil {
+		utils.Logger.WithError(err).Error("Failed to close database")
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		utils.Logger.WithError(err).Error("Failed to flush traces")
 	}
+
+	utils.Logger.Info("Server stopped")
 }
 
-func setupRoutes(router *gin.Engine, cfg *config.Config) {
+func reloadCertificatesOnSIGHUP(ctx context.Context, certs *server.CertReloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := certs.Reload(); err != nil {
+				utils.Logger.WithError(err).Error("Failed to reload TLS certificate")
+				continue
+			}
+			utils.Logger.Info("TLS certificate reloaded")
+		}
+	}
+}
+
+// setupRoutes wires the application and returns a function that stops its
+// background work
+func setupRoutes(router *gin.Engine, cfg *config.Config) (stopBackground func()) {
 	db := database.GetDB()
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	otpRepo := repository.NewOTPRepository(db)
 	otpAttemptRepo := repository.NewOTPAttemptRepository(db)
+	otpLockoutRepo := repository.NewOTPLockoutRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db)
+	transactor := repository.NewTransactor(db, cfg.Database.TransactionTimeout)
+
+	checks := []health.Check{health.DatabaseCheck(cfg.Health.DatabaseTimeout)}
+	var redisClient *goredis.Client
+	closeRedis := func() {}
+	if cfg.OTP.Store == "redis" || cfg.RateLimit.Store == "redis" {
+		var err error
+		redisClient, err = redisstore.Connect(context.Background(), &cfg.Redis)
+		if err != nil {
+			utils.Logger.WithError(err).Fatal("Failed to connect to Redis")
+		}
+		closeRedis = func() {
+			if err := redisClient.Close(); err != nil {
+				utils.Logger.WithError(err).Error("Failed to close Redis client")
+			}
+		}
+		checks = append(checks, health.RedisCheck(redisClient, cfg.Health.RedisTimeout))
+	}
+
+	switch cfg.OTP.Store {
+	case "redis":
+		otpRepo = redisstore.NewOTPRepository(redisClient, cfg.Redis.KeyPrefix)
+		otpAttemptRepo = redisstore.NewOTPAttemptRepository(redisClient, cfg.Redis.KeyPrefix, cfg.OTP.RateWindow)
+		transactor = repository.NewTransactorWithOTPs(db, cfg.Database.TransactionTimeout, otpRepo)
+	case "memory":
+		store := memory.NewStore()
+		otpRepo = memory.NewOTPRepository(store)
+		otpAttemptRepo = memory.NewOTPAttemptRepository(store)
+		transactor = repository.NewTransactorWithOTPs(db, cfg.Database.TransactionTimeout, otpRepo)
+	}
+
+	// rateLimitRepo is only set for the database store, which needs cleaning
+	var rateLimits ratelimit.Store
+	var rateLimitRepo interfaces.RateLimitRepository
+	switch cfg.RateLimit.Store {
+	case "memory":
+		rateLimits = ratelimit.NewMemoryStore()
+	case "redis":
+		rateLimits = redisstore.NewRateLimitRepository(redisClient, cfg.Redis.KeyPrefix)
+	default:
+		rateLimitRepo = repository.NewRateLimitRepository(db)
+		rateLimits = rateLimitRepo
+	}
+
+	otpSender, err := sms.NewSender(&cfg.SMS)
+	if err != nil {
+		utils.Logger.WithError(err).Fatal("Failed to initialize SMS sender")
+	}
+
+	var challengeVerifier interfaces.ChallengeVerifier
+	if cfg.Challenge.Enabled {
+		challengeVerifier, err = challenge.NewVerifier(&cfg.Challenge, rateLimits)
+		if err != nil {
+			utils.Logger.WithError(err).Fatal("Failed to initialize challenge verifier")
+		}
+	}
 
 	// Initialize services with dependency injection
-	otpService := services.NewOTPService(cfg, otpRepo, otpAttemptRepo, userRepo)
-	userService := services.NewUserService(userRepo)
+	otpService := services.NewOTPService(cfg, otpRepo, otpAttemptRepo, otpLockoutRepo, userRepo, transactor, rateLimits, otpSender)
+	keyring, err := services.NewKeyring(&cfg.JWT)
+	if err != nil {
+		utils.Logger.WithError(err).Fatal("Failed to load JWT signing keys")
+	}
+	stopKeyringReloader := services.StartKeyringReloader(keyring, &cfg.JWT)
+
+	revocationStore := services.NewRevocationStore(cfg, revokedTokenRepo, userRepo, sessionRepo)
+	tokenService := services.NewTokenService(cfg, keyring, sessionRepo, userRepo, revocationStore)
+	userService := services.NewUserService(userRepo, revocationStore)
+	challengeGate := services.NewChallengeGate(&cfg.Challenge, challengeVerifier, rateLimits)
+
+	var jobScheduler *scheduler.Scheduler
+	if cfg.Scheduler.Enabled {
+		var locker scheduler.Locker = scheduler.NewLocalLocker()
+		var sqlDB *sql.DB
+		if cfg.Database.Driver == database.DriverPostgres {
+			sqlDB, err = db.DB()
+			if err != nil {
+				utils.Logger.WithError(err).Fatal("Failed to initialize scheduler")
+			}
+			locker = scheduler.NewPostgresLocker(sqlDB)
+		}
+
+		jobScheduler = scheduler.New(locker)
+		for _, job := range services.MaintenanceJobs(&cfg.Scheduler, otpService, tokenService, rateLimitRepo) {
+			jobScheduler.Register(job)
+		}
+
+		// Job leases each pin a connection; keep DB_MAX_OPEN_CONNS for requests
+		if sqlDB != nil && cfg.Database.MaxOpenConns > 0 {
+			sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns + jobScheduler.Jobs())
+		}
+		jobScheduler.Start(context.Background())
+	}
 
 	// Initialize handlers with dependency injection
-	authHandler := handlers.NewAuthHandler(otpService, cfg)
+	authHandler := handlers.NewAuthHandler(otpService, tokenService, challengeGate, cfg)
+	sessionHandler := handlers.NewSessionHandler(tokenService)
 	userHandler := handlers.NewUserHandler(userService)
+	jwksHandler := handlers.NewJWKSHandler(tokenService)
+	adminHandler := handlers.NewAdminHandler(jobScheduler)
+	checks = append(checks,
+		health.SMSCheck(otpSender, cfg.SMS.Provider, cfg.Health.SMSTimeout),
+		health.KeyringCheck(keyring, cfg.JWT.Issuer, cfg.Health.KeyringTimeout),
+	)
+	healthHandler := handlers.NewHealthHandler(health.NewChecker(checks...))
 	versionHandler := handlers.NewVersionHandler(Version, BuildTime, GitCommit, gin.Mode())
 
 	// Swagger endpoint
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	if cfg.Metrics.Enabled {
+		router.GET(cfg.Metrics.Path, gin.WrapH(metrics.Handler()))
+	}
+
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
 	router.GET("/version", versionHandler.GetVersion)
 	router.GET("/api/info", versionHandler.GetAPIInfo)
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	limits := &cfg.RateLimit
+	perIP := rateLimited(limits, limits.IPRequests, middleware.ClientIPKey,
+		ratelimit.NewTokenBucket(rateLimits, "ip", limits.IPRequests, limits.IPWindow))
+	perUser := rateLimited(limits, limits.UserRequests, middleware.UserKey,
+		ratelimit.NewTokenBucket(rateLimits, "user", limits.UserRequests, limits.UserWindow))
+	sendOTPPerIP := rateLimited(limits, limits.SendOTPRequests, middleware.ClientIPKey,
+		ratelimit.NewSlidingWindow(rateLimits, "send-otp:ip", limits.SendOTPRequests, limits.SendOTPWindow))
+	verifyOTPPerIP := rateLimited(limits, limits.VerifyOTPRequests, middleware.ClientIPKey,
+		ratelimit.NewSlidingWindow(rateLimits, "verify-otp:ip", limits.VerifyOTPRequests, limits.VerifyOTPWindow))
 
 	api := router.Group("/api/v1")
+	api.Use(perIP...)
 
 	authGroup := api.Group("/auth")
 	{
-		authGroup.POST("/send-otp", authHandler.SendOTP)
-		authGroup.POST("/verify-otp", authHandler.VerifyOTP)
+		authGroup.POST("/send-otp", append(sendOTPPerIP, authHandler.SendOTP)...)
+		authGroup.POST("/verify-otp", append(verifyOTPPerIP, authHandler.VerifyOTP)...)
+		authGroup.POST("/refresh", authHandler.RefreshToken)
 
 		authProtected := authGroup.Group("")
-		authProtected.Use(middleware.AuthMiddleware(cfg))
+		authProtected.Use(middleware.AuthMiddleware(tokenService))
+		authProtected.Use(perUser...)
 		authProtected.GET("/profile", authHandler.GetProfile)
+		authProtected.POST("/logout", authHandler.Logout)
+		authProtected.POST("/logout-all", authHandler.LogoutAll)
+		authProtected.GET("/sessions", sessionHandler.GetSessions)
+		authProtected.DELETE("/sessions", sessionHandler.RevokeOtherSessions)
+		authProtected.DELETE("/sessions/:id", sessionHandler.RevokeSession)
 	}
 
 	userGroup := api.Group("/users")
-	userGroup.Use(middleware.AuthMiddleware(cfg))
+	userGroup.Use(
+		middleware.AuthMiddleware(tokenService),
+		middleware.RequireRole(models.RoleAdmin, models.RoleSupport),
+	)
+	userGroup.Use(perUser...)
+	{
+		readUsers := middleware.RequirePermission(models.PermissionUsersRead)
+		userGroup.GET("", readUsers, userHandler.GetUsers)
+		userGroup.GET("/stats", readUsers, userHandler.GetUserStats)
+		userGroup.GET("/:id", readUsers, userHandler.GetUser)
+		userGroup.PUT("/:id/role", middleware.RequirePermission(models.PermissionUsersManage), userHandler.UpdateUserRole)
+	}
+
+	adminGroup := api.Group("/admin")
+	adminGroup.Use(
+		middleware.AuthMiddleware(tokenService),
+		middleware.RequireRole(models.RoleAdmin),
+	)
+	adminGroup.Use(perUser...)
 	{
-		userGroup.GET("", userHandler.GetUsers)
-		userGroup.GET("/stats", userHandler.GetUserStats)
-		userGroup.GET("/:id", userHandler.GetUser)
+		adminGroup.GET("/jobs", adminHandler.GetJobs)
 	}
 
 	utils.Logger.Info("Routes configured successfully")
+
+	return func() {
+		if jobScheduler != nil {
+			jobScheduler.Stop()
+		}
+		stopKeyringReloader()
+		closeRedis()
+	}
+}
+
+// rateLimited returns the middleware enforcing limiter, or none when rate
+// limiting or this particular limit is turned off
+func rateLimited(cfg *config.RateLimitConfig, requests int, key middleware.RateLimitKey, limiter ratelimit.Limiter) []gin.HandlerFunc {
+	if !cfg.Enabled || requests == 0 {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.RateLimit(limiter, key)}
 }
@@ -0,0 +1,73 @@
+package challenge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPVerifier(t *testing.T) {
+	var form map[string]string
+
+	siteverify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("response") == "good-token" {
+			w.Write([]byte(`{"success": true}`))
+			return
+		}
+		w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	}))
+	defer siteverify.Close()
+
+	verifier := NewHTTPVerifier(config.ChallengeConfig{
+		SiteKey:   "site-key",
+		SecretKey: "secret-key",
+		Timeout:   time.Second,
+	}, ProviderTurnstile, siteverify.URL)
+
+	challenge, err := verifier.Issue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, TypeCaptcha, challenge.Type)
+	assert.Equal(t, ProviderTurnstile, challenge.Provider)
+	assert.Equal(t, "site-key", challenge.SiteKey)
+
+	ok, err := verifier.Verify(context.Background(), "good-token", "192.0.2.1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"secret":   "secret-key",
+		"response": "good-token",
+		"sitekey":  "site-key",
+		"remoteip": "192.0.2.1",
+	}, form)
+
+	ok, err = verifier.Verify(context.Background(), "bad-token", "192.0.2.1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestHTTPVerifierReportsProviderErrors(t *testing.T) {
+	siteverify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+	}))
+	defer siteverify.Close()
+
+	verifier := NewHTTPVerifier(config.ChallengeConfig{Timeout: time.Second}, ProviderHCaptcha, siteverify.URL)
+
+	_, err := verifier.Verify(context.Background(), "token", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
@@ -0,0 +1,117 @@
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+	"go-auth/pkg/ratelimit"
+)
+
+// maxCounterLength bounds the part of a solution the client chose
+const maxCounterLength = 32
+
+// proofOfWork is a hashcash-style challenge. Tokens are signed rather than
+// stored, so any replica can check a solution; only spent tokens are kept,
+// until they expire, so each one is good for a single request.
+type proofOfWork struct {
+	secret     []byte
+	difficulty int
+	ttl        time.Duration
+	store      ratelimit.Store
+	now        func() time.Time
+}
+
+func NewProofOfWork(cfg config.ChallengeConfig, store ratelimit.Store) interfaces.ChallengeVerifier {
+	return &proofOfWork{
+		secret:     []byte(cfg.PoWSecret),
+		difficulty: cfg.PoWDifficulty,
+		ttl:        cfg.PoWTTL,
+		store:      store,
+		now:        time.Now,
+	}
+}
+
+// Issue signs a token of the form <difficulty>.<expiry>.<nonce>.<mac>
+func (p *proofOfWork) Issue(ctx context.Context) (*models.Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	payload := fmt.Sprintf("%d.%d.%s", p.difficulty, p.now().Add(p.ttl).Unix(), hex.EncodeToString(nonce))
+	return &models.Challenge{
+		Type:       TypePoW,
+		Token:      payload + "." + p.sign(payload),
+		Difficulty: p.difficulty,
+	}, nil
+}
+
+// Verify checks a solution of the form <token>:<counter>
+func (p *proofOfWork) Verify(ctx context.Context, solution, clientIP string) (bool, error) {
+	token, counter, ok := strings.Cut(solution, ":")
+	if !ok || counter == "" || len(counter) > maxCounterLength {
+		return false, nil
+	}
+
+	fields := strings.Split(token, ".")
+	if len(fields) != 4 {
+		return false, nil
+	}
+
+	payload := strings.Join(fields[:3], ".")
+	if !hmac.Equal([]byte(fields[3]), []byte(p.sign(payload))) {
+		return false, nil
+	}
+
+	// The signature vouches for the difficulty and expiry
+	difficulty, _ := strconv.Atoi(fields[0])
+	expiry, _ := strconv.ParseInt(fields[1], 10, 64)
+	remaining := time.Unix(expiry, 0).Sub(p.now())
+	if remaining <= 0 {
+		return false, nil
+	}
+
+	if leadingZeroBits(sha256.Sum256([]byte(solution))) < difficulty {
+		return false, nil
+	}
+
+	var spent bool
+	err := p.store.Update(ctx, "challenge:pow:"+fields[2], remaining, func(state ratelimit.State) ratelimit.State {
+		spent = state.Count > 0
+		state.Count = 1
+		return state
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return !spent, nil
+}
+
+func (p *proofOfWork) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		n += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return n
+}
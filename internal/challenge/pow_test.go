@@ -0,0 +1,127 @@
+package challenge
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogger()
+	utils.Logger.SetLevel(logrus.PanicLevel)
+	os.Exit(m.Run())
+}
+
+func newProofOfWork(difficulty int) *proofOfWork {
+	return NewProofOfWork(config.ChallengeConfig{
+		PoWSecret:     "test-secret",
+		PoWDifficulty: difficulty,
+		PoWTTL:        time.Minute,
+	}, ratelimit.NewMemoryStore()).(*proofOfWork)
+}
+
+// solve does what a client does: count until the hash has enough zero bits
+func solve(token string, difficulty int) string {
+	for counter := 0; ; counter++ {
+		solution := token + ":" + strconv.Itoa(counter)
+		if leadingZeroBits(sha256.Sum256([]byte(solution))) >= difficulty {
+			return solution
+		}
+	}
+}
+
+func TestProofOfWork(t *testing.T) {
+	ctx := context.Background()
+	pow := newProofOfWork(8)
+
+	challenge, err := pow.Issue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, TypePoW, challenge.Type)
+	assert.Equal(t, 8, challenge.Difficulty)
+
+	solution := solve(challenge.Token, challenge.Difficulty)
+	ok, err := pow.Verify(ctx, solution, "")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = pow.Verify(ctx, solution, "")
+	require.NoError(t, err)
+	assert.False(t, ok, "a solution is good for one request")
+}
+
+func TestProofOfWorkRejectsBadSolutions(t *testing.T) {
+	ctx := context.Background()
+	pow := newProofOfWork(8)
+
+	challenge, err := pow.Issue(ctx)
+	require.NoError(t, err)
+	solution := solve(challenge.Token, challenge.Difficulty)
+
+	// Changing what the token says breaks its signature
+	fields := strings.Split(challenge.Token, ".")
+	easier := strings.Join(append([]string{"1"}, fields[1:]...), ".")
+	fields[2] = strings.Repeat("0", len(fields[2]))
+	otherNonce := strings.Join(fields, ".")
+
+	unsolved := challenge.Token + ":0"
+	for i := 1; leadingZeroBits(sha256.Sum256([]byte(unsolved))) >= 8; i++ {
+		unsolved = challenge.Token + ":" + strconv.Itoa(i)
+	}
+
+	for name, bad := range map[string]string{
+		"empty":            "",
+		"no counter":       challenge.Token,
+		"unsolved":         unsolved,
+		"counter too long": challenge.Token + ":" + strings.Repeat("1", maxCounterLength+1),
+		"not a token":      "hello:1",
+		"lower difficulty": solve(easier, 1),
+		"other nonce":      solve(otherNonce, 8),
+	} {
+		ok, err := pow.Verify(ctx, bad, "")
+		require.NoError(t, err, name)
+		assert.False(t, ok, name)
+	}
+
+	// Expired challenges are refused even when solved
+	pow.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	ok, err := pow.Verify(ctx, solution, "")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestNewVerifierRequiresProviderSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.ChallengeConfig
+		expectError bool
+	}{
+		{"PoW", config.ChallengeConfig{Provider: ProviderPoW, PoWSecret: "secret"}, false},
+		{"PoW without secret", config.ChallengeConfig{Provider: ProviderPoW}, true},
+		{"hCaptcha", config.ChallengeConfig{Provider: ProviderHCaptcha, SiteKey: "site", SecretKey: "secret"}, false},
+		{"Turnstile without keys", config.ChallengeConfig{Provider: ProviderTurnstile}, true},
+		{"Unknown provider", config.ChallengeConfig{Provider: "riddle"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(&tt.cfg, ratelimit.NewMemoryStore())
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
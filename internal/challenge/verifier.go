@@ -0,0 +1,54 @@
+// Package challenge implements the challenges send-otp asks abusive-looking
+// clients to solve: a built-in proof of work, or a CAPTCHA checked through
+// an hCaptcha or Turnstile compatible siteverify API.
+package challenge
+
+import (
+	"fmt"
+	"strings"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+	"go-auth/pkg/ratelimit"
+)
+
+const (
+	ProviderPoW       = "pow"
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderTurnstile = "turnstile"
+)
+
+// Types of models.Challenge
+const (
+	TypePoW     = "pow"
+	TypeCaptcha = "captcha"
+)
+
+// siteverifyURLs are the default endpoints of the CAPTCHA providers
+var siteverifyURLs = map[string]string{
+	ProviderHCaptcha:  "https://api.hcaptcha.com/siteverify",
+	ProviderTurnstile: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+
+// NewVerifier builds the verifier selected by cfg.Provider. The proof of
+// work remembers spent solutions in store.
+func NewVerifier(cfg *config.ChallengeConfig, store ratelimit.Store) (interfaces.ChallengeVerifier, error) {
+	switch provider := strings.ToLower(cfg.Provider); provider {
+	case ProviderPoW:
+		if cfg.PoWSecret == "" {
+			return nil, fmt.Errorf("CHALLENGE_POW_SECRET is required for the pow provider")
+		}
+		return NewProofOfWork(*cfg, store), nil
+	case ProviderHCaptcha, ProviderTurnstile:
+		if cfg.SiteKey == "" || cfg.SecretKey == "" {
+			return nil, fmt.Errorf("CHALLENGE_SITE_KEY and CHALLENGE_SECRET_KEY are required for the %s provider", provider)
+		}
+		verifyURL := cfg.VerifyURL
+		if verifyURL == "" {
+			verifyURL = siteverifyURLs[provider]
+		}
+		return NewHTTPVerifier(*cfg, provider, verifyURL), nil
+	default:
+		return nil, fmt.Errorf("unknown challenge provider: %s", cfg.Provider)
+	}
+}
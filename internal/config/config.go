@@ -2,74 +2,474 @@ package config
 
 import (
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	defaultJWTSecret = "your-super-secret-jwt-key"
+	defaultOTPPepper = "your-otp-pepper"
+)
+
 type Config struct {
-	Port     string
-	Database DatabaseConfig
-	JWT      JWTConfig
-	OTP      OTPConfig
+	Environment string
+	Port        string
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Redis       RedisConfig
+	JWT         JWTConfig
+	OTP         OTPConfig
+	SMS         SMSConfig
+	CORS        CORSConfig
+	RateLimit   RateLimitConfig
+	Fraud       FraudConfig
+	Challenge   ChallengeConfig
+	Log         LogConfig
+	Scheduler   SchedulerConfig
+	Health      HealthConfig
+	Metrics     MetricsConfig
+	Tracing     TracingConfig
+}
+
+// ServerConfig holds HTTP server timeouts and optional TLS. The certificate
+// and key files are re-read when they change on disk.
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSReloadInterval time.Duration
+
+	// TrustedProxies are the addresses or CIDR ranges whose X-Forwarded-For
+	// header is believed when resolving the client IP. Empty trusts none.
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
+	// Driver is postgres, or sqlite for development and hermetic tests
+	Driver          string
+	SQLitePath      string
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	DBName          string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// Deadlines for a single statement and for a whole transaction; a
+	// shorter request deadline still wins
+	ReadTimeout        time.Duration
+	WriteTimeout       time.Duration
+	TransactionTimeout time.Duration
+
+	// AutoMigrate applies pending migrations at startup. Without it the
+	// server only checks the schema version and refuses to start on a
+	// mismatch.
+	AutoMigrate bool
+}
+
+// RedisConfig is the Redis server used by the redis OTP and rate-limit
+// stores. The URL may carry client options such as
+// ?dial_timeout=1s&read_timeout=500ms.
+type RedisConfig struct {
+	URL       string
+	KeyPrefix string
+}
+
+// RateLimitConfig sets the request limits enforced by middleware. A limit
+// of zero requests turns it off. Store also holds the per phone number OTP
+// request limit, which applies even when Enabled is false.
+type RateLimitConfig struct {
+	Enabled bool
+	// Store is memory (per replica), database or redis
+	Store string
+
+	// Token buckets: bursts of up to Requests, refilled over Window
+	IPRequests   int
+	IPWindow     time.Duration
+	UserRequests int
+	UserWindow   time.Duration
+
+	// Sliding windows per client IP on the OTP endpoints
+	SendOTPRequests   int
+	SendOTPWindow     time.Duration
+	VerifyOTPRequests int
+	VerifyOTPWindow   time.Duration
+}
+
+// FraudConfig defends send-otp against SMS pumping, where bots request
+// codes to premium-rate numbers whose operators share the revenue with the
+// attacker. Budgets of zero sends are off; the counters live in the
+// RateLimit store.
+type FraudConfig struct {
+	Enabled bool
+
+	// Number prefixes such as +44 or +8827. An empty allow list allows every
+	// destination that is not denied.
+	AllowedPrefixes []string
+	DeniedPrefixes  []string
+
+	// Codes sent per hour and per day, by client IP, by country calling
+	// code, by range of 1000 consecutive numbers and overall
+	IPHourly      int
+	IPDaily       int
+	CountryHourly int
+	CountryDaily  int
+	RangeHourly   int
+	RangeDaily    int
+	GlobalHourly  int
+	GlobalDaily   int
+
+	// A country calling code is suspended for BreakerCooldown after a
+	// BreakerWindow in which at least BreakerMinSends codes were sent to it
+	// and fewer than BreakerMinConversion of them were verified. Zero
+	// BreakerMinSends turns the breaker off.
+	BreakerMinSends      int
+	BreakerMinConversion float64
+	BreakerWindow        time.Duration
+	BreakerCooldown      time.Duration
+}
+
+// ChallengeConfig makes send-otp ask for a solved CAPTCHA or proof of work
+// once a client IP or a country calling code sends more than a soft
+// threshold of requests, rather than refusing them outright
+type ChallengeConfig struct {
+	Enabled bool
+	// Provider is pow (built-in proof of work), hcaptcha or turnstile
+	Provider string
+
+	// send-otp requests per hour above which a challenge is required;
+	// zero is off
+	IPHourly      int
+	CountryHourly int
+
+	// PoWDifficulty is the number of leading zero bits a solution's
+	// SHA-256 must have; each one doubles the average work. PoWSecret signs
+	// issued challenges, and must be shared by all replicas.
+	PoWDifficulty int
+	PoWSecret     string
+	PoWTTL        time.Duration
+
+	// SiteKey and SecretKey are issued by the CAPTCHA provider. VerifyURL
+	// overrides its siteverify endpoint.
+	SiteKey   string
+	SecretKey string
+	VerifyURL string
+	Timeout   time.Duration
 }
 
 type JWTConfig struct {
-	Secret string
+	Secret              string
+	Algorithm           string
+	PrivateKeyFile      string
+	KeyID               string
+	KeysDir             string
+	KeyringReload       time.Duration
+	Issuer              string
+	AccessTokenTTL      time.Duration
+	RefreshTokenTTL     time.Duration
+	RevocationCacheTTL  time.Duration
+	RevocationCacheSize int
 }
 
 type OTPConfig struct {
-	ExpiryTime  time.Duration
-	MaxAttempts int
-	RateWindow  time.Duration
+	// Store keeps OTPs and request attempts in the database, in Redis where
+	// they expire on their own, or in process memory for development
+	Store             string
+	ExpiryTime        time.Duration
+	MaxAttempts       int
+	RateWindow        time.Duration
+	Pepper            string
+	MaxVerifyAttempts int
+	LockoutBase       time.Duration
+	LockoutMax        time.Duration
+}
+
+type SMSConfig struct {
+	Provider        string
+	MessageTemplate string
+	HTTP            HTTPSMSConfig
+	SMPP            SMPPConfig
+	FilePath        string
+}
+
+type HTTPSMSConfig struct {
+	URL     string
+	APIKey  string
+	From    string
+	Timeout time.Duration
+}
+
+type SMPPConfig struct {
+	Address    string
+	SystemID   string
+	Password   string
+	SystemType string
+	SourceAddr string
+	Timeout    time.Duration
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type LogConfig struct {
+	Level  string
+	Format string
+
+	// SlowQueryThreshold logs statements running longer at warn level; 0
+	// disables it
+	SlowQueryThreshold time.Duration
+
+	// RevealOTPCodes writes generated OTP codes to the log. Only allowed in
+	// development.
+	RevealOTPCodes bool
 }
 
+// SchedulerConfig controls the background cleanup jobs. Each job has its own
+// interval; retention is how long rows are kept after they stop being useful.
+type SchedulerConfig struct {
+	Enabled              bool
+	JobTimeout           time.Duration
+	OTPInterval          time.Duration
+	OTPRetention         time.Duration
+	OTPAttemptInterval   time.Duration
+	OTPAttemptRetention  time.Duration
+	SessionInterval      time.Duration
+	SessionRetention     time.Duration
+	RevokedTokenInterval time.Duration
+	RateLimitInterval    time.Duration
+}
+
+// HealthConfig holds the time limit of each readiness check. Checks run in
+// parallel, so the slowest one bounds the probe.
+type HealthConfig struct {
+	DatabaseTimeout time.Duration
+	RedisTimeout    time.Duration
+	SMSTimeout      time.Duration
+	KeyringTimeout  time.Duration
+}
+
+// MetricsConfig controls the Prometheus endpoint. The endpoint is served on the
+// main port, so it should not be routed from the public ingress.
+type MetricsConfig struct {
+	Enabled bool
+	Path    string
+}
+
+// TracingConfig controls OpenTelemetry tracing. Spans are exported over
+// OTLP/HTTP to Endpoint, e.g. a local collector on localhost:4318.
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+}
+
+// LoadConfig reads the configuration. Each setting is taken from the first of:
+// the environment (including a .env file), the YAML file named by CONFIG_FILE,
+// and the built-in default. The result is validated before it is returned.
 func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 
+	l, err := newLoader(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return nil, err
+	}
+
 	config := &Config{
-		Port: getEnv("PORT", "8080"),
+		Environment: l.getEnv("ENVIRONMENT", "development"),
+		Port:        l.getEnv("PORT", "8080"),
+		Server: ServerConfig{
+			ReadTimeout:       time.Duration(l.getEnvAsInt("SERVER_READ_TIMEOUT_SECONDS", 15)) * time.Second,
+			ReadHeaderTimeout: time.Duration(l.getEnvAsInt("SERVER_READ_HEADER_TIMEOUT_SECONDS", 5)) * time.Second,
+			WriteTimeout:      time.Duration(l.getEnvAsInt("SERVER_WRITE_TIMEOUT_SECONDS", 30)) * time.Second,
+			IdleTimeout:       time.Duration(l.getEnvAsInt("SERVER_IDLE_TIMEOUT_SECONDS", 120)) * time.Second,
+			ShutdownTimeout:   time.Duration(l.getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
+			TLSCertFile:       l.getEnv("TLS_CERT_FILE", ""),
+			TLSKeyFile:        l.getEnv("TLS_KEY_FILE", ""),
+			TLSReloadInterval: time.Duration(l.getEnvAsInt("TLS_RELOAD_SECONDS", 60)) * time.Second,
+			TrustedProxies:    l.getEnvAsList("TRUSTED_PROXIES", nil),
+		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "password"),
-			DBName:   getEnv("DB_NAME", "go_auth"),
+			Driver:          l.getEnv("DB_DRIVER", "postgres"),
+			SQLitePath:      l.getEnv("DB_SQLITE_PATH", "go-auth.db"),
+			Host:            l.getEnv("DB_HOST", "localhost"),
+			Port:            l.getEnv("DB_PORT", "5432"),
+			User:            l.getEnv("DB_USER", "postgres"),
+			Password:        l.getEnv("DB_PASSWORD", "password"),
+			DBName:          l.getEnv("DB_NAME", "go_auth"),
+			SSLMode:         l.getEnv("DB_SSLMODE", "disable"),
+			MaxOpenConns:    l.getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    l.getEnvAsInt("DB_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetime: time.Duration(l.getEnvAsInt("DB_CONN_MAX_LIFETIME_MINUTES", 30)) * time.Minute,
+			ConnMaxIdleTime: time.Duration(l.getEnvAsInt("DB_CONN_MAX_IDLE_MINUTES", 5)) * time.Minute,
+
+			ReadTimeout:        time.Duration(l.getEnvAsInt("DB_READ_TIMEOUT_MS", 2000)) * time.Millisecond,
+			WriteTimeout:       time.Duration(l.getEnvAsInt("DB_WRITE_TIMEOUT_MS", 3000)) * time.Millisecond,
+			TransactionTimeout: time.Duration(l.getEnvAsInt("DB_TRANSACTION_TIMEOUT_MS", 5000)) * time.Millisecond,
+
+			AutoMigrate: l.getEnvAsBool("DB_AUTO_MIGRATE", true),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-super-secret-jwt-key"),
+			Secret:              l.getEnv("JWT_SECRET", defaultJWTSecret),
+			Algorithm:           l.getEnv("JWT_ALGORITHM", "HS256"),
+			PrivateKeyFile:      l.getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			KeyID:               l.getEnv("JWT_KEY_ID", ""),
+			KeysDir:             l.getEnv("JWT_KEYS_DIR", ""),
+			KeyringReload:       time.Duration(l.getEnvAsInt("JWT_KEYRING_RELOAD_SECONDS", 60)) * time.Second,
+			Issuer:              l.getEnv("JWT_ISSUER", ""),
+			AccessTokenTTL:      time.Duration(l.getEnvAsInt("JWT_ACCESS_TTL_MINUTES", 24*60)) * time.Minute,
+			RefreshTokenTTL:     time.Duration(l.getEnvAsInt("JWT_REFRESH_TTL_HOURS", 30*24)) * time.Hour,
+			RevocationCacheTTL:  time.Duration(l.getEnvAsInt("JWT_REVOCATION_CACHE_SECONDS", 30)) * time.Second,
+			RevocationCacheSize: l.getEnvAsInt("JWT_REVOCATION_CACHE_SIZE", 10000),
+		},
+		Redis: RedisConfig{
+			URL:       l.getEnv("REDIS_URL", ""),
+			KeyPrefix: l.getEnv("REDIS_KEY_PREFIX", "go-auth:"),
 		},
 		OTP: OTPConfig{
-			ExpiryTime:  2 * time.Minute,
-			MaxAttempts: 3,
-			RateWindow:  10 * time.Minute,
+			Store:             l.getEnv("OTP_STORE", "database"),
+			ExpiryTime:        time.Duration(l.getEnvAsInt("OTP_EXPIRY_MINUTES", 2)) * time.Minute,
+			MaxAttempts:       l.getEnvAsInt("OTP_MAX_ATTEMPTS", 3),
+			RateWindow:        time.Duration(l.getEnvAsInt("OTP_RATE_WINDOW_MINUTES", 10)) * time.Minute,
+			Pepper:            l.getEnv("OTP_PEPPER", defaultOTPPepper),
+			MaxVerifyAttempts: l.getEnvAsInt("OTP_MAX_VERIFY_ATTEMPTS", 5),
+			LockoutBase:       time.Duration(l.getEnvAsInt("OTP_LOCKOUT_BASE_SECONDS", 60)) * time.Second,
+			LockoutMax:        time.Duration(l.getEnvAsInt("OTP_LOCKOUT_MAX_MINUTES", 60)) * time.Minute,
+		},
+		SMS: SMSConfig{
+			Provider:        l.getEnv("SMS_PROVIDER", "console"),
+			MessageTemplate: l.getEnv("SMS_MESSAGE_TEMPLATE", "Your verification code is {code}"),
+			HTTP: HTTPSMSConfig{
+				URL:     l.getEnv("SMS_HTTP_URL", ""),
+				APIKey:  l.getEnv("SMS_HTTP_API_KEY", ""),
+				From:    l.getEnv("SMS_FROM", ""),
+				Timeout: time.Duration(l.getEnvAsInt("SMS_HTTP_TIMEOUT_SECONDS", 10)) * time.Second,
+			},
+			SMPP: SMPPConfig{
+				Address:    l.getEnv("SMPP_ADDRESS", ""),
+				SystemID:   l.getEnv("SMPP_SYSTEM_ID", ""),
+				Password:   l.getEnv("SMPP_PASSWORD", ""),
+				SystemType: l.getEnv("SMPP_SYSTEM_TYPE", ""),
+				SourceAddr: l.getEnv("SMS_FROM", ""),
+				Timeout:    time.Duration(l.getEnvAsInt("SMPP_TIMEOUT_SECONDS", 10)) * time.Second,
+			},
+			FilePath: l.getEnv("SMS_FILE_PATH", ""),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   l.getEnvAsList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+			AllowedMethods:   l.getEnvAsList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+			AllowedHeaders:   l.getEnvAsList("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Authorization", "API-Version"}),
+			ExposedHeaders:   l.getEnvAsList("CORS_EXPOSED_HEADERS", []string{"API-Version", "X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}),
+			AllowCredentials: l.getEnvAsBool("CORS_ALLOW_CREDENTIALS", true),
+			MaxAge:           time.Duration(l.getEnvAsInt("CORS_MAX_AGE_HOURS", 12)) * time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           l.getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Store:             l.getEnv("RATE_LIMIT_STORE", "database"),
+			IPRequests:        l.getEnvAsInt("RATE_LIMIT_IP_REQUESTS", 300),
+			IPWindow:          time.Duration(l.getEnvAsInt("RATE_LIMIT_IP_WINDOW_SECONDS", 60)) * time.Second,
+			UserRequests:      l.getEnvAsInt("RATE_LIMIT_USER_REQUESTS", 600),
+			UserWindow:        time.Duration(l.getEnvAsInt("RATE_LIMIT_USER_WINDOW_SECONDS", 60)) * time.Second,
+			SendOTPRequests:   l.getEnvAsInt("RATE_LIMIT_SEND_OTP_REQUESTS", 10),
+			SendOTPWindow:     time.Duration(l.getEnvAsInt("RATE_LIMIT_SEND_OTP_WINDOW_MINUTES", 60)) * time.Minute,
+			VerifyOTPRequests: l.getEnvAsInt("RATE_LIMIT_VERIFY_OTP_REQUESTS", 30),
+			VerifyOTPWindow:   time.Duration(l.getEnvAsInt("RATE_LIMIT_VERIFY_OTP_WINDOW_MINUTES", 10)) * time.Minute,
+		},
+		Fraud: FraudConfig{
+			Enabled:              l.getEnvAsBool("FRAUD_ENABLED", true),
+			AllowedPrefixes:      l.getEnvAsList("FRAUD_ALLOWED_PREFIXES", nil),
+			DeniedPrefixes:       l.getEnvAsList("FRAUD_DENIED_PREFIXES", nil),
+			IPHourly:             l.getEnvAsInt("FRAUD_IP_HOURLY", 0),
+			IPDaily:              l.getEnvAsInt("FRAUD_IP_DAILY", 50),
+			CountryHourly:        l.getEnvAsInt("FRAUD_COUNTRY_HOURLY", 0),
+			CountryDaily:         l.getEnvAsInt("FRAUD_COUNTRY_DAILY", 0),
+			RangeHourly:          l.getEnvAsInt("FRAUD_RANGE_HOURLY", 20),
+			RangeDaily:           l.getEnvAsInt("FRAUD_RANGE_DAILY", 100),
+			GlobalHourly:         l.getEnvAsInt("FRAUD_GLOBAL_HOURLY", 0),
+			GlobalDaily:          l.getEnvAsInt("FRAUD_GLOBAL_DAILY", 0),
+			BreakerMinSends:      l.getEnvAsInt("FRAUD_BREAKER_MIN_SENDS", 50),
+			BreakerMinConversion: l.getEnvAsFloat("FRAUD_BREAKER_MIN_CONVERSION", 0.2),
+			BreakerWindow:        time.Duration(l.getEnvAsInt("FRAUD_BREAKER_WINDOW_MINUTES", 15)) * time.Minute,
+			BreakerCooldown:      time.Duration(l.getEnvAsInt("FRAUD_BREAKER_COOLDOWN_MINUTES", 60)) * time.Minute,
+		},
+		Challenge: ChallengeConfig{
+			Enabled:       l.getEnvAsBool("CHALLENGE_ENABLED", false),
+			Provider:      l.getEnv("CHALLENGE_PROVIDER", "pow"),
+			IPHourly:      l.getEnvAsInt("CHALLENGE_IP_HOURLY", 5),
+			CountryHourly: l.getEnvAsInt("CHALLENGE_COUNTRY_HOURLY", 0),
+			PoWDifficulty: l.getEnvAsInt("CHALLENGE_POW_DIFFICULTY", 20),
+			PoWSecret:     l.getEnv("CHALLENGE_POW_SECRET", ""),
+			PoWTTL:        time.Duration(l.getEnvAsInt("CHALLENGE_POW_TTL_SECONDS", 300)) * time.Second,
+			SiteKey:       l.getEnv("CHALLENGE_SITE_KEY", ""),
+			SecretKey:     l.getEnv("CHALLENGE_SECRET_KEY", ""),
+			VerifyURL:     l.getEnv("CHALLENGE_VERIFY_URL", ""),
+			Timeout:       time.Duration(l.getEnvAsInt("CHALLENGE_TIMEOUT_SECONDS", 5)) * time.Second,
+		},
+		Log: LogConfig{
+			Level:  l.getEnv("LOG_LEVEL", "info"),
+			Format: l.getEnv("LOG_FORMAT", ""),
+
+			SlowQueryThreshold: time.Duration(l.getEnvAsInt("LOG_SLOW_QUERY_MS", 200)) * time.Millisecond,
+			RevealOTPCodes:     l.getEnvAsBool("LOG_REVEAL_OTP_CODES", false),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:              l.getEnvAsBool("SCHEDULER_ENABLED", true),
+			JobTimeout:           time.Duration(l.getEnvAsInt("SCHEDULER_JOB_TIMEOUT_SECONDS", 60)) * time.Second,
+			OTPInterval:          time.Duration(l.getEnvAsInt("CLEANUP_OTP_INTERVAL_MINUTES", 10)) * time.Minute,
+			OTPRetention:         time.Duration(l.getEnvAsInt("CLEANUP_OTP_RETENTION_HOURS", 24)) * time.Hour,
+			OTPAttemptInterval:   time.Duration(l.getEnvAsInt("CLEANUP_OTP_ATTEMPT_INTERVAL_MINUTES", 10)) * time.Minute,
+			OTPAttemptRetention:  time.Duration(l.getEnvAsInt("CLEANUP_OTP_ATTEMPT_RETENTION_HOURS", 24)) * time.Hour,
+			SessionInterval:      time.Duration(l.getEnvAsInt("CLEANUP_SESSION_INTERVAL_MINUTES", 60)) * time.Minute,
+			SessionRetention:     time.Duration(l.getEnvAsInt("CLEANUP_SESSION_RETENTION_DAYS", 30)) * 24 * time.Hour,
+			RevokedTokenInterval: time.Duration(l.getEnvAsInt("CLEANUP_REVOKED_TOKEN_INTERVAL_MINUTES", 60)) * time.Minute,
+			RateLimitInterval:    time.Duration(l.getEnvAsInt("CLEANUP_RATE_LIMIT_INTERVAL_MINUTES", 10)) * time.Minute,
+		},
+		Metrics: MetricsConfig{
+			Enabled: l.getEnvAsBool("METRICS_ENABLED", true),
+			Path:    l.getEnv("METRICS_PATH", "/metrics"),
+		},
+		Tracing: TracingConfig{
+			Enabled:     l.getEnvAsBool("TRACING_ENABLED", false),
+			ServiceName: l.getEnv("TRACING_SERVICE_NAME", "go-auth"),
+			Endpoint:    l.getEnv("TRACING_ENDPOINT", "localhost:4318"),
+			Insecure:    l.getEnvAsBool("TRACING_INSECURE", true),
+			SampleRatio: l.getEnvAsFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+		Health: HealthConfig{
+			DatabaseTimeout: time.Duration(l.getEnvAsInt("HEALTH_DATABASE_TIMEOUT_MS", 500)) * time.Millisecond,
+			RedisTimeout:    time.Duration(l.getEnvAsInt("HEALTH_REDIS_TIMEOUT_MS", 200)) * time.Millisecond,
+			SMSTimeout:      time.Duration(l.getEnvAsInt("HEALTH_SMS_TIMEOUT_MS", 800)) * time.Millisecond,
+			KeyringTimeout:  time.Duration(l.getEnvAsInt("HEALTH_KEYRING_TIMEOUT_MS", 200)) * time.Millisecond,
 		},
 	}
 
-	return config, nil
-}
+	if err := l.err(); err != nil {
+		return nil, err
+	}
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
-	return defaultValue
+
+	return config, nil
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
+func (c *Config) IsProduction() bool {
+	return c.Environment == "production"
 }
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, "8080", cfg.Port)
+	assert.Equal(t, 2*time.Minute, cfg.OTP.ExpiryTime)
+	assert.Equal(t, 3, cfg.OTP.MaxAttempts)
+	assert.Equal(t, 10*time.Minute, cfg.OTP.RateWindow)
+	assert.Equal(t, []string{"*"}, cfg.CORS.AllowedOrigins)
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+port: 9090
+otp:
+  expiry_minutes: 5
+  max_attempts: 4
+jwt:
+  issuer: https://auth.example.com
+  access_ttl_minutes: 15
+cors:
+  allowed_origins:
+    - https://app.example.com
+    - https://admin.example.com
+db:
+  max_open_conns: 50
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("OTP_MAX_ATTEMPTS", "6")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, "9090", cfg.Port, "file overrides default")
+	assert.Equal(t, 5*time.Minute, cfg.OTP.ExpiryTime)
+	assert.Equal(t, 6, cfg.OTP.MaxAttempts, "environment overrides file")
+	assert.Equal(t, "https://auth.example.com", cfg.JWT.Issuer)
+	assert.Equal(t, 15*time.Minute, cfg.JWT.AccessTokenTTL)
+	assert.Equal(t, []string{"https://app.example.com", "https://admin.example.com"}, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+}
+
+func TestLoadConfigRejectsMalformedValues(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("OTP_EXPIRY_MINUTES", "two")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "maybe")
+
+	_, err := LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "OTP_EXPIRY_MINUTES")
+	assert.Contains(t, err.Error(), "CORS_ALLOW_CREDENTIALS")
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	_, err := LoadConfig()
+	assert.Error(t, err)
+}
+
+func TestValidateProductionSecrets(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("ENVIRONMENT", "production")
+	t.Setenv("OTP_STORE", "memory")
+
+	_, err := LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "JWT_SECRET must be changed")
+	assert.Contains(t, err.Error(), "OTP_PEPPER must be changed")
+
+	assert.Contains(t, err.Error(), "SMS_PROVIDER=console is only allowed when ENVIRONMENT=development")
+	assert.Contains(t, err.Error(), "OTP_STORE=memory is only allowed when ENVIRONMENT=development")
+
+	t.Setenv("JWT_SECRET", "a-long-random-secret-used-only-in-this-test")
+	t.Setenv("OTP_PEPPER", "a-random-pepper")
+	t.Setenv("SMS_PROVIDER", "http")
+	t.Setenv("SMS_HTTP_URL", "https://sms-gateway.example.com/send")
+	t.Setenv("OTP_STORE", "database")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.IsProduction())
+}
+
+func TestValidate(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+
+	cfg.JWT.Algorithm = "none"
+	cfg.OTP.LockoutMax = time.Second
+	cfg.SMS.Provider = "http"
+	cfg.Server.TLSCertFile = "/etc/go-auth/tls.crt"
+	cfg.Tracing.SampleRatio = 1.5
+	cfg.Environment = "staging"
+	cfg.Log.RevealOTPCodes = true
+	cfg.Database.Driver = "mysql"
+	cfg.OTP.Store = "redis"
+	cfg.RateLimit.Store = "disk"
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "load-balancer"}
+	cfg.Fraud.DeniedPrefixes = []string{"+882", "UK"}
+	cfg.Fraud.BreakerMinConversion = 0
+	cfg.Challenge.Enabled = true
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "JWT_ALGORITHM")
+	assert.Contains(t, err.Error(), "OTP_LOCKOUT_MAX_MINUTES")
+	assert.Contains(t, err.Error(), "SMS_HTTP_URL")
+	assert.Contains(t, err.Error(), "TLS_KEY_FILE")
+	assert.Contains(t, err.Error(), "TRACING_SAMPLE_RATIO")
+	assert.Contains(t, err.Error(), "LOG_REVEAL_OTP_CODES")
+	assert.Contains(t, err.Error(), "DB_DRIVER")
+	assert.Contains(t, err.Error(), "REDIS_URL")
+	assert.Contains(t, err.Error(), "RATE_LIMIT_STORE")
+	assert.Contains(t, err.Error(), `got "load-balancer"`)
+	assert.Contains(t, err.Error(), `FRAUD_DENIED_PREFIXES must hold number prefixes such as +44, got "UK"`)
+	assert.Contains(t, err.Error(), "FRAUD_BREAKER_MIN_CONVERSION")
+	assert.Contains(t, err.Error(), "CHALLENGE_POW_SECRET")
+}
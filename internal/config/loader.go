@@ -0,0 +1,141 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loader resolves settings by their environment variable name. A config file
+// uses the same names split into nested sections, so `jwt: {access_ttl_minutes: 15}`
+// sets JWT_ACCESS_TTL_MINUTES. Environment variables take precedence.
+type loader struct {
+	file   map[string]string
+	errors []error
+}
+
+func newLoader(path string) (*loader, error) {
+	l := &loader{file: map[string]string{}}
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	flatten("", document, l.file)
+	return l, nil
+}
+
+func flatten(prefix string, value interface{}, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+			flatten(name, child, out)
+		}
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		out[prefix] = strings.Join(items, ",")
+	case nil:
+		out[prefix] = ""
+	default:
+		out[prefix] = fmt.Sprint(v)
+	}
+}
+
+func (l *loader) lookup(key string) (string, bool) {
+	if value := os.Getenv(key); value != "" {
+		return value, true
+	}
+	if value, ok := l.file[key]; ok && value != "" {
+		return value, true
+	}
+	return "", false
+}
+
+func (l *loader) getEnv(key, defaultValue string) string {
+	if value, ok := l.lookup(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+func (l *loader) getEnvAsInt(key string, defaultValue int) int {
+	value, ok := l.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		l.errors = append(l.errors, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return defaultValue
+	}
+	return intValue
+}
+
+func (l *loader) getEnvAsBool(key string, defaultValue bool) bool {
+	value, ok := l.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		l.errors = append(l.errors, fmt.Errorf("%s must be true or false, got %q", key, value))
+		return defaultValue
+	}
+	return boolValue
+}
+
+func (l *loader) getEnvAsFloat(key string, defaultValue float64) float64 {
+	value, ok := l.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		l.errors = append(l.errors, fmt.Errorf("%s must be a number, got %q", key, value))
+		return defaultValue
+	}
+	return floatValue
+}
+
+// getEnvAsList reads a comma separated list
+func (l *loader) getEnvAsList(key string, defaultValue []string) []string {
+	value, ok := l.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (l *loader) err() error {
+	return errors.Join(l.errors...)
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// insecureSecrets are the placeholder values shipped in code and .env.example
+var insecureSecrets = map[string]bool{
+	defaultJWTSecret: true,
+	defaultOTPPepper: true,
+	"your-super-secret-jwt-key-change-this-in-production": true,
+	"change-this-otp-pepper-in-production":                true,
+}
+
+// Validate reports every invalid setting at once. In production it also
+// refuses the placeholder secrets.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port > 0 && port < 65536, "PORT must be a port number, got %q", c.Port)
+
+	check(c.Server.ReadTimeout > 0, "SERVER_READ_TIMEOUT_SECONDS must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "SERVER_READ_HEADER_TIMEOUT_SECONDS must be positive")
+	check(c.Server.WriteTimeout > 0, "SERVER_WRITE_TIMEOUT_SECONDS must be positive")
+	check(c.Server.IdleTimeout > 0, "SERVER_IDLE_TIMEOUT_SECONDS must be positive")
+	check(c.Server.ShutdownTimeout > 0, "SERVER_SHUTDOWN_TIMEOUT_SECONDS must be positive")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""),
+		"TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(c.Server.TLSReloadInterval >= 0, "TLS_RELOAD_SECONDS must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "TRUSTED_PROXIES must hold IP addresses or CIDR ranges, got %q", proxy)
+	}
+
+	switch c.Database.Driver {
+	case "postgres":
+	case "sqlite":
+		check(c.Database.SQLitePath != "", "DB_SQLITE_PATH is required for the sqlite driver")
+	default:
+		problems = append(problems, fmt.Sprintf("DB_DRIVER must be postgres or sqlite, got %q", c.Database.Driver))
+	}
+	check(c.Database.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+	check(c.Database.ReadTimeout > 0, "DB_READ_TIMEOUT_MS must be positive")
+	check(c.Database.WriteTimeout > 0, "DB_WRITE_TIMEOUT_MS must be positive")
+	check(c.Database.TransactionTimeout >= c.Database.WriteTimeout,
+		"DB_TRANSACTION_TIMEOUT_MS must not be shorter than DB_WRITE_TIMEOUT_MS")
+
+	switch c.JWT.Algorithm {
+	case "HS256":
+		check(c.JWT.KeysDir != "" || c.JWT.Secret != "", "JWT_SECRET is required for HS256")
+	case "RS256", "ES256", "EdDSA":
+		check(c.JWT.KeysDir != "" || c.JWT.PrivateKeyFile != "",
+			"JWT_PRIVATE_KEY_FILE is required for %s", c.JWT.Algorithm)
+	default:
+		problems = append(problems, fmt.Sprintf("JWT_ALGORITHM must be HS256, RS256, ES256 or EdDSA, got %q", c.JWT.Algorithm))
+	}
+	check(c.JWT.AccessTokenTTL > 0, "JWT_ACCESS_TTL_MINUTES must be positive")
+	check(c.JWT.RefreshTokenTTL > c.JWT.AccessTokenTTL, "JWT_REFRESH_TTL_HOURS must be longer than the access token lifetime")
+	check(c.JWT.RevocationCacheSize > 0, "JWT_REVOCATION_CACHE_SIZE must be positive")
+	check(c.JWT.RevocationCacheTTL >= 0, "JWT_REVOCATION_CACHE_SECONDS must not be negative")
+
+	check(c.OTP.ExpiryTime > 0, "OTP_EXPIRY_MINUTES must be positive")
+	check(c.OTP.MaxAttempts > 0, "OTP_MAX_ATTEMPTS must be positive")
+	check(c.OTP.RateWindow > 0, "OTP_RATE_WINDOW_MINUTES must be positive")
+	check(c.OTP.Pepper != "", "OTP_PEPPER is required")
+	check(c.OTP.MaxVerifyAttempts > 0, "OTP_MAX_VERIFY_ATTEMPTS must be positive")
+	check(c.OTP.LockoutBase > 0, "OTP_LOCKOUT_BASE_SECONDS must be positive")
+	check(c.OTP.LockoutMax >= c.OTP.LockoutBase, "OTP_LOCKOUT_MAX_MINUTES must not be shorter than OTP_LOCKOUT_BASE_SECONDS")
+
+	switch c.OTP.Store {
+	case "database":
+	case "redis":
+		check(c.Redis.URL != "", "REDIS_URL is required for the redis OTP store")
+	case "memory":
+		check(c.Environment == "development", "OTP_STORE=memory is only allowed when ENVIRONMENT=development")
+	default:
+		problems = append(problems, fmt.Sprintf("OTP_STORE must be database, redis or memory, got %q", c.OTP.Store))
+	}
+
+	switch c.RateLimit.Store {
+	case "memory", "database":
+	case "redis":
+		check(c.Redis.URL != "", "REDIS_URL is required for the redis rate-limit store")
+	default:
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_STORE must be memory, database or redis, got %q", c.RateLimit.Store))
+	}
+	check(c.RateLimit.IPRequests >= 0, "RATE_LIMIT_IP_REQUESTS must not be negative")
+	check(c.RateLimit.IPWindow > 0, "RATE_LIMIT_IP_WINDOW_SECONDS must be positive")
+	check(c.RateLimit.UserRequests >= 0, "RATE_LIMIT_USER_REQUESTS must not be negative")
+	check(c.RateLimit.UserWindow > 0, "RATE_LIMIT_USER_WINDOW_SECONDS must be positive")
+	check(c.RateLimit.SendOTPRequests >= 0, "RATE_LIMIT_SEND_OTP_REQUESTS must not be negative")
+	check(c.RateLimit.SendOTPWindow > 0, "RATE_LIMIT_SEND_OTP_WINDOW_MINUTES must be positive")
+	check(c.RateLimit.VerifyOTPRequests >= 0, "RATE_LIMIT_VERIFY_OTP_REQUESTS must not be negative")
+	check(c.RateLimit.VerifyOTPWindow > 0, "RATE_LIMIT_VERIFY_OTP_WINDOW_MINUTES must be positive")
+
+	for _, prefix := range c.Fraud.AllowedPrefixes {
+		check(validPrefix(prefix), "FRAUD_ALLOWED_PREFIXES must hold number prefixes such as +44, got %q", prefix)
+	}
+	for _, prefix := range c.Fraud.DeniedPrefixes {
+		check(validPrefix(prefix), "FRAUD_DENIED_PREFIXES must hold number prefixes such as +44, got %q", prefix)
+	}
+	check(c.Fraud.IPHourly >= 0 && c.Fraud.IPDaily >= 0, "FRAUD_IP_HOURLY and FRAUD_IP_DAILY must not be negative")
+	check(c.Fraud.CountryHourly >= 0 && c.Fraud.CountryDaily >= 0, "FRAUD_COUNTRY_HOURLY and FRAUD_COUNTRY_DAILY must not be negative")
+	check(c.Fraud.RangeHourly >= 0 && c.Fraud.RangeDaily >= 0, "FRAUD_RANGE_HOURLY and FRAUD_RANGE_DAILY must not be negative")
+	check(c.Fraud.GlobalHourly >= 0 && c.Fraud.GlobalDaily >= 0, "FRAUD_GLOBAL_HOURLY and FRAUD_GLOBAL_DAILY must not be negative")
+	check(c.Fraud.BreakerMinSends >= 0, "FRAUD_BREAKER_MIN_SENDS must not be negative")
+	if c.Fraud.BreakerMinSends > 0 {
+		check(c.Fraud.BreakerMinConversion > 0 && c.Fraud.BreakerMinConversion <= 1, "FRAUD_BREAKER_MIN_CONVERSION must be above 0 and at most 1")
+		check(c.Fraud.BreakerWindow > 0, "FRAUD_BREAKER_WINDOW_MINUTES must be positive")
+		check(c.Fraud.BreakerCooldown > 0, "FRAUD_BREAKER_COOLDOWN_MINUTES must be positive")
+	}
+
+	if c.Challenge.Enabled {
+		switch c.Challenge.Provider {
+		case "pow":
+			check(c.Challenge.PoWSecret != "", "CHALLENGE_POW_SECRET is required for the pow provider")
+			check(c.Challenge.PoWDifficulty > 0 && c.Challenge.PoWDifficulty <= 32, "CHALLENGE_POW_DIFFICULTY must be between 1 and 32")
+			check(c.Challenge.PoWTTL > 0, "CHALLENGE_POW_TTL_SECONDS must be positive")
+		case "hcaptcha", "turnstile":
+			check(c.Challenge.SiteKey != "" && c.Challenge.SecretKey != "",
+				"CHALLENGE_SITE_KEY and CHALLENGE_SECRET_KEY are required for the %s provider", c.Challenge.Provider)
+			check(c.Challenge.Timeout > 0, "CHALLENGE_TIMEOUT_SECONDS must be positive")
+		default:
+			problems = append(problems, fmt.Sprintf("CHALLENGE_PROVIDER must be pow, hcaptcha or turnstile, got %q", c.Challenge.Provider))
+		}
+	}
+	check(c.Challenge.IPHourly >= 0, "CHALLENGE_IP_HOURLY must not be negative")
+	check(c.Challenge.CountryHourly >= 0, "CHALLENGE_COUNTRY_HOURLY must not be negative")
+
+	switch c.SMS.Provider {
+	case "console", "file":
+		check(c.Environment == "development",
+			"SMS_PROVIDER=%s is only allowed when ENVIRONMENT=development", c.SMS.Provider)
+	case "http":
+		check(c.SMS.HTTP.URL != "", "SMS_HTTP_URL is required for the http provider")
+	case "smpp":
+		check(c.SMS.SMPP.Address != "", "SMPP_ADDRESS is required for the smpp provider")
+	default:
+		problems = append(problems, fmt.Sprintf("SMS_PROVIDER must be console, file, http or smpp, got %q", c.SMS.Provider))
+	}
+
+	check(len(c.CORS.AllowedOrigins) > 0, "CORS_ALLOWED_ORIGINS must not be empty")
+	check(c.CORS.MaxAge >= 0, "CORS_MAX_AGE_HOURS must not be negative")
+
+	if c.Scheduler.Enabled {
+		check(c.Scheduler.JobTimeout > 0, "SCHEDULER_JOB_TIMEOUT_SECONDS must be positive")
+		check(c.Scheduler.OTPInterval > 0, "CLEANUP_OTP_INTERVAL_MINUTES must be positive")
+		check(c.Scheduler.OTPAttemptInterval > 0, "CLEANUP_OTP_ATTEMPT_INTERVAL_MINUTES must be positive")
+		check(c.Scheduler.SessionInterval > 0, "CLEANUP_SESSION_INTERVAL_MINUTES must be positive")
+		check(c.Scheduler.RevokedTokenInterval > 0, "CLEANUP_REVOKED_TOKEN_INTERVAL_MINUTES must be positive")
+		check(c.Scheduler.RateLimitInterval > 0, "CLEANUP_RATE_LIMIT_INTERVAL_MINUTES must be positive")
+	}
+	check(c.Scheduler.OTPRetention >= 0, "CLEANUP_OTP_RETENTION_HOURS must not be negative")
+	check(c.Scheduler.OTPAttemptRetention >= c.OTP.RateWindow,
+		"CLEANUP_OTP_ATTEMPT_RETENTION_HOURS must cover OTP_RATE_WINDOW_MINUTES")
+	check(c.Scheduler.SessionRetention >= 0, "CLEANUP_SESSION_RETENTION_DAYS must not be negative")
+
+	check(c.Health.DatabaseTimeout > 0, "HEALTH_DATABASE_TIMEOUT_MS must be positive")
+	check(c.Health.SMSTimeout > 0, "HEALTH_SMS_TIMEOUT_MS must be positive")
+	check(c.Health.KeyringTimeout > 0, "HEALTH_KEYRING_TIMEOUT_MS must be positive")
+
+	check(!c.Metrics.Enabled || strings.HasPrefix(c.Metrics.Path, "/"), "METRICS_PATH must start with /")
+
+	if c.Tracing.Enabled {
+		check(c.Tracing.Endpoint != "", "TRACING_ENDPOINT is required when tracing is enabled")
+		check(c.Tracing.ServiceName != "", "TRACING_SERVICE_NAME is required when tracing is enabled")
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	check(c.Log.Format == "" || c.Log.Format == "json" || c.Log.Format == "text",
+		"LOG_FORMAT must be json or text, got %q", c.Log.Format)
+	check(c.Log.SlowQueryThreshold >= 0, "LOG_SLOW_QUERY_MS must not be negative")
+	check(!c.Log.RevealOTPCodes || c.Environment == "development",
+		"LOG_REVEAL_OTP_CODES is only allowed when ENVIRONMENT=development")
+
+	if c.IsProduction() {
+		check(c.JWT.Algorithm != "HS256" || c.JWT.KeysDir != "" || !insecureSecrets[c.JWT.Secret],
+			"JWT_SECRET must be changed from the default in production")
+		check(c.JWT.Algorithm != "HS256" || c.JWT.KeysDir != "" || len(c.JWT.Secret) >= 32,
+			"JWT_SECRET must be at least 32 characters in production")
+		check(!insecureSecrets[c.OTP.Pepper], "OTP_PEPPER must be changed from the default in production")
+		check(!c.Challenge.Enabled || c.Challenge.Provider != "pow" || len(c.Challenge.PoWSecret) >= 32,
+			"CHALLENGE_POW_SECRET must be at least 32 characters in production")
+		check(c.Database.Driver != "sqlite", "DB_DRIVER=sqlite is not supported in production")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// validPrefix accepts an optional + followed by up to 15 digits
+func validPrefix(prefix string) bool {
+	digits := strings.TrimPrefix(prefix, "+")
+	if digits == "" || len(digits) > 15 {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
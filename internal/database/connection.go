@@ -1,61 +1,168 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"go-auth/internal/config"
-	"go-auth/internal/models"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// migratedVersion is the schema version this process verified at startup
+var migratedVersion int
+
+// Supported values of DB_DRIVER
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 func ConnectDatabase(cfg *config.Config) error {
+	gormConfig := &gorm.Config{
+		Logger: NewGormLogger(cfg.Log.SlowQueryThreshold),
+	}
+
+	var err error
+	if cfg.Database.Driver == DriverSQLite {
+		DB, err = OpenSQLite(cfg.Database.SQLitePath, gormConfig)
+		if err != nil {
+			return err
+		}
+
+		log.Println("SQLite database opened successfully")
+		return nil
+	}
+
 	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
 		cfg.Database.Host,
 		cfg.Database.Port,
 		cfg.Database.User,
 		cfg.Database.Password,
 		cfg.Database.DBName,
+		cfg.Database.SSLMode,
 	)
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+	DB, err = gorm.Open(postgres.Open(dsn), gormConfig)
 
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to configure connection pool: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+
 	log.Println("Database connection established successfully")
 	return nil
 }
 
-func RunMigrations() error {
+// OpenSQLite opens the SQLite database at path, or a private in-memory one
+// for ":memory:". The pool holds a single connection that is never recycled:
+// SQLite allows one writer at a time, and an in-memory database lives only as
+// long as its connection. Timestamps are stored as text, so values written by
+// processes in different time zones do not compare correctly.
+func OpenSQLite(path string, gormConfig *gorm.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"), gormConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure connection pool: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetMaxIdleConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+
+	return db, nil
+}
+
+// RunMigrations brings the schema to the version embedded in this binary,
+// or with apply false only checks it. Either way it refuses a database at any
+// other version, such as one migrated by a newer release.
+func RunMigrations(ctx context.Context, apply bool) error {
 	if DB == nil {
 		return fmt.Errorf("database not connected")
 	}
 
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.OTP{},
-		&models.OTPAttempt{},
-	)
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+
+	migrations, err := EmbeddedMigrations(DB.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	migrator, err := NewMigrator(sqlDB, DB.Dialector.Name(), migrations)
+	if err != nil {
+		return err
+	}
 
+	if apply {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+		}
+	}
+
+	version, pending, err := migrator.Version(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("schema is at version %d but %d migrations are pending, up to version %d; run `migrate up`",
+			version, len(pending), migrator.Latest())
 	}
 
-	log.Println("Database migrations completed successfully")
+	migratedVersion = version
+
+	log.Printf("Database schema is at version %d", version)
 	return nil
 }
 
+// MigrationVersion returns the schema version verified at startup, or 0 when
+// RunMigrations has not run
+func MigrationVersion() int {
+	return migratedVersion
+}
+
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// Close releases the connection pool
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+
+	log.Println("Database connection closed")
+	return nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-auth/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	gormutils "gorm.io/gorm/utils"
+)
+
+// gormLogger sends GORM's output through the request-scoped logrus logger,
+// so statements carry the request_id and user_id of the request that ran
+// them. Every statement is logged at debug level, slow ones at warn. Bound
+// values are never logged, only the SQL with its placeholders.
+type gormLogger struct {
+	slowThreshold time.Duration
+}
+
+// NewGormLogger returns a GORM logger that warns about statements slower than
+// slowThreshold; 0 disables the warning
+func NewGormLogger(slowThreshold time.Duration) logger.Interface {
+	return &gormLogger{slowThreshold: slowThreshold}
+}
+
+// LogMode is a no-op: the level is taken from the logrus logger
+func (l *gormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	utils.LogWithContext(ctx).Infof(msg, data...)
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	utils.LogWithContext(ctx).Warnf(msg, data...)
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	utils.LogWithContext(ctx).Errorf(msg, data...)
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	entry := utils.LogWithContext(ctx)
+
+	level, message := logrus.DebugLevel, "Database Query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, message = logrus.ErrorLevel, "Database Query Failed"
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold:
+		level, message = logrus.WarnLevel, "Slow Database Query"
+	}
+
+	if !entry.Logger.IsLevelEnabled(level) {
+		return
+	}
+
+	sql, rows := fc()
+	fields := logrus.Fields{
+		"sql":         sql,
+		"duration_ms": elapsed.Milliseconds(),
+		"source":      gormutils.FileWithLineNum(),
+		"type":        "database_query",
+	}
+	if rows >= 0 {
+		fields["rows"] = rows
+	}
+	if level == logrus.ErrorLevel {
+		fields["error"] = err.Error()
+	}
+	if level == logrus.WarnLevel {
+		fields["threshold_ms"] = l.slowThreshold.Milliseconds()
+	}
+
+	entry.WithFields(fields).Log(level, message)
+}
+
+// ParamsFilter drops the bound values so phone numbers and token hashes do
+// not end up in the logs
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"go-auth/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func captureLogs(t *testing.T, level logrus.Level) *test.Hook {
+	t.Helper()
+
+	previous := utils.Logger
+	utils.Logger = logrus.New()
+	utils.Logger.SetOutput(io.Discard)
+	utils.Logger.SetLevel(level)
+	t.Cleanup(func() { utils.Logger = previous })
+
+	return test.NewLocal(utils.Logger)
+}
+
+func TestGormLoggerUsesRequestLogger(t *testing.T) {
+	hook := captureLogs(t, logrus.DebugLevel)
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 NewGormLogger(time.Second),
+	})
+	require.NoError(t, err)
+
+	ctx := utils.WithLogFields(context.Background(), map[string]interface{}{"request_id": "req-1"})
+	var found []widget
+	db.WithContext(ctx).Where("name = ?", "+15551234567").Find(&found)
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.DebugLevel, entry.Level)
+	assert.Equal(t, "req-1", entry.Data["request_id"])
+	assert.Contains(t, entry.Data["sql"], "$1")
+	assert.NotContains(t, entry.Data["sql"], "+15551234567", "bound values must not be logged")
+}
+
+func TestGormLoggerLevels(t *testing.T) {
+	hook := captureLogs(t, logrus.InfoLevel)
+	l := NewGormLogger(10 * time.Millisecond)
+	statement := func() (string, int64) { return "SELECT 1", 1 }
+
+	l.Trace(context.Background(), time.Now(), statement, nil)
+	assert.Empty(t, hook.Entries, "fast statements are only logged at debug level")
+
+	l.Trace(context.Background(), time.Now(), statement, gorm.ErrRecordNotFound)
+	assert.Empty(t, hook.Entries, "a missing record is not an error")
+
+	l.Trace(context.Background(), time.Now().Add(-time.Second), statement, nil)
+	require.Len(t, hook.Entries, 1)
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+	assert.Equal(t, "Slow Database Query", hook.LastEntry().Message)
+
+	l.Trace(context.Background(), time.Now(), statement, assert.AnError)
+	require.Len(t, hook.Entries, 2)
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+	assert.Equal(t, assert.AnError.Error(), hook.LastEntry().Data["error"])
+}
@@ -0,0 +1,446 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var embeddedMigrations embed.FS
+
+// migrationDirs holds each driver's migrations within embeddedMigrations.
+// Both sets must have the same versions.
+var migrationDirs = map[string]string{
+	DriverPostgres: "migrations",
+	DriverSQLite:   "migrations/sqlite",
+}
+
+// migrationLockKey serialises migrations across replicas. It is an arbitrary
+// constant in the bigint advisory lock space.
+const migrationLockKey int64 = 0x676f2d61757468 // "go-auth"
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Migration is one versioned schema change with the SQL to apply and revert it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// EmbeddedMigrations returns the migrations compiled into the binary for
+// driver
+func EmbeddedMigrations(driver string) ([]Migration, error) {
+	dir, ok := migrationDirs[driver]
+	if !ok {
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+
+	sub, err := fs.Sub(embeddedMigrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	return LoadMigrations(sub)
+}
+
+// SchemaVersion returns the version the embedded migrations bring the
+// schema to
+var SchemaVersion = sync.OnceValue(func() int {
+	migrations, err := EmbeddedMigrations(DriverPostgres)
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+})
+
+// LoadMigrations reads NNNN_name.up.sql and NNNN_name.down.sql pairs from
+// fsys, ordered by version. Every version needs both files.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected file %q among migrations", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		if version <= 0 {
+			return nil, fmt.Errorf("migration %q must have a positive version", entry.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// CreateMigration writes an empty up/down pair to dir, numbered after the
+// latest migration there, and returns the paths written. When dir has a
+// sqlite subdirectory the pair is added there too, with the same version.
+func CreateMigration(dir, name string) ([]string, error) {
+	if !migrationNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("migration name must be lower case letters, digits and underscores, got %q", name)
+	}
+
+	dirs := []string{dir}
+	if info, err := os.Stat(filepath.Join(dir, "sqlite")); err == nil && info.IsDir() {
+		dirs = append(dirs, filepath.Join(dir, "sqlite"))
+	}
+
+	version := 1
+	for _, dir := range dirs {
+		existing, err := LoadMigrations(os.DirFS(dir))
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 && existing[len(existing)-1].Version >= version {
+			version = existing[len(existing)-1].Version + 1
+		}
+	}
+
+	var paths []string
+	for _, dir := range dirs {
+		base := filepath.Join(dir, fmt.Sprintf("%04d_%s", version, name))
+		up, down := base+".up.sql", base+".down.sql"
+
+		if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write migration: %w", err)
+		}
+		if err := os.WriteFile(down, []byte("-- revert "+name+"\n"), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write migration: %w", err)
+		}
+		paths = append(paths, up, down)
+	}
+
+	return paths, nil
+}
+
+// migrationDialect holds the bookkeeping statements that differ per driver
+type migrationDialect struct {
+	lock, unlock string // empty when the driver needs no lock
+	tableExists  string
+	createTable  string
+	insert       string
+	delete       string
+}
+
+var migrationDialects = map[string]migrationDialect{
+	DriverPostgres: {
+		lock:        "SELECT pg_advisory_lock($1)",
+		unlock:      "SELECT pg_advisory_unlock($1)",
+		tableExists: "SELECT to_regclass('schema_migrations') IS NOT NULL",
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    bigint      PRIMARY KEY,
+			name       text        NOT NULL,
+			applied_at timestamptz NOT NULL
+		)`,
+		insert: "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		delete: "DELETE FROM schema_migrations WHERE version = $1",
+	},
+	// A SQLite database is only ever opened by one process
+	DriverSQLite: {
+		tableExists: "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')",
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    integer  PRIMARY KEY,
+			name       text     NOT NULL,
+			applied_at datetime NOT NULL
+		)`,
+		insert: "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		delete: "DELETE FROM schema_migrations WHERE version = ?",
+	},
+}
+
+// Migrator applies and reverts migrations, recording them in the
+// schema_migrations table. Each migration runs in its own transaction, and on
+// Postgres an advisory lock keeps replicas from migrating at the same time.
+type Migrator struct {
+	db         *sql.DB
+	dialect    migrationDialect
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, driver string, migrations []Migration) (*Migrator, error) {
+	dialect, ok := migrationDialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("migrations are not supported for database driver %q", driver)
+	}
+
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Latest returns the version of the newest known migration
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration in order and returns those applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkKnown(versions); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, m.dialect.insert, migration.Version, migration.Name, time.Now().UTC())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations and returns those reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkKnown(versions); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+
+			err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, m.dialect.delete, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists every known migration and when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	versions, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkKnown(versions); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := versions[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Version returns the highest applied version, or 0 on an empty database,
+// and the migrations still to apply
+func (m *Migrator) Version(ctx context.Context) (int, []Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	version := 0
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+			continue
+		}
+		version = status.Version
+	}
+
+	return version, pending, nil
+}
+
+// checkKnown refuses a database migrated by a newer binary
+func (m *Migrator) checkKnown(versions map[int]time.Time) error {
+	known := make(map[int]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+
+	for version := range versions {
+		if !known[version] {
+			return fmt.Errorf("database has migration %d applied, which this binary does not know; it was migrated by a newer version", version)
+		}
+	}
+
+	return nil
+}
+
+// locked runs fn on a connection holding the migration lock, waiting for
+// another replica's migration to finish first
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect.lock != "" {
+		if _, err := conn.ExecContext(ctx, m.dialect.lock, migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer func() {
+			// A fresh context: the lock must be released even if ctx is done
+			unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn.ExecContext(unlockCtx, m.dialect.unlock, migrationLockKey)
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	versions := map[int]time.Time{}
+
+	// Nothing has been migrated yet
+	var exists bool
+	if err := conn.QueryRowContext(ctx, m.dialect.tableExists).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	if !exists {
+		return versions, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+func inTransaction(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	return tx.Commit()
+}
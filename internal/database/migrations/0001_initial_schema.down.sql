@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS otp_lockouts;
+DROP TABLE IF EXISTS otp_attempts;
+DROP TABLE IF EXISTS otps;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,80 @@
+-- Baseline schema. Databases created by the old AutoMigrate startup already
+-- have these tables and indexes; IF NOT EXISTS lets them adopt version 1 in
+-- place, and ADD COLUMN IF NOT EXISTS brings tables created by an older
+-- release up to date.
+
+CREATE TABLE IF NOT EXISTS users (
+    id            uuid        NOT NULL DEFAULT gen_random_uuid(),
+    phone_number  text        NOT NULL,
+    role          varchar(32) NOT NULL DEFAULT 'user',
+    token_version bigint      NOT NULL DEFAULT 0,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    PRIMARY KEY (id)
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar(32) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version bigint NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone_number ON users (phone_number);
+
+CREATE TABLE IF NOT EXISTS otps (
+    id              uuid        NOT NULL DEFAULT gen_random_uuid(),
+    phone_number    text        NOT NULL,
+    code_hash       text        NOT NULL DEFAULT '',
+    created_at      timestamptz,
+    expires_at      timestamptz NOT NULL,
+    is_used         boolean     DEFAULT false,
+    failed_attempts bigint      NOT NULL DEFAULT 0,
+    PRIMARY KEY (id)
+);
+-- Deployments from before codes were hashed still have the plaintext code
+-- column; migration 0003 drops it
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS code_hash text NOT NULL DEFAULT '';
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS failed_attempts bigint NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_otps_phone_number ON otps (phone_number);
+
+CREATE TABLE IF NOT EXISTS otp_attempts (
+    id           uuid NOT NULL DEFAULT gen_random_uuid(),
+    phone_number text NOT NULL,
+    attempt_time timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_otp_attempts_phone_number ON otp_attempts (phone_number);
+
+CREATE TABLE IF NOT EXISTS otp_lockouts (
+    phone_number text        NOT NULL,
+    level        bigint      NOT NULL DEFAULT 0,
+    locked_until timestamptz NOT NULL,
+    updated_at   timestamptz,
+    PRIMARY KEY (phone_number)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id                 uuid         NOT NULL DEFAULT gen_random_uuid(),
+    user_id            uuid         NOT NULL,
+    refresh_token_hash text         NOT NULL,
+    user_agent         varchar(512),
+    client_ip          varchar(64),
+    device_name        varchar(100),
+    last_seen_at       timestamptz,
+    expires_at         timestamptz  NOT NULL,
+    revoked_at         timestamptz,
+    created_at         timestamptz,
+    updated_at         timestamptz,
+    PRIMARY KEY (id)
+);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent varchar(512);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_ip varchar(64);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_name varchar(100);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        text        NOT NULL,
+    user_id    uuid        NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (jti)
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_user_id ON revoked_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
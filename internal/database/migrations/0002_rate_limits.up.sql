@@ -0,0 +1,11 @@
+-- State of the rate limiters using the database store. Rows are updated in
+-- place and removed by the rate_limit_cleanup job once expired.
+CREATE TABLE rate_limits (
+    key        text             NOT NULL,
+    count      double precision NOT NULL DEFAULT 0,
+    previous   double precision NOT NULL DEFAULT 0,
+    time       timestamptz      NOT NULL,
+    expires_at timestamptz      NOT NULL,
+    PRIMARY KEY (key)
+);
+CREATE INDEX idx_rate_limits_expires_at ON rate_limits (expires_at);
@@ -0,0 +1,2 @@
+-- The dropped codes are gone; this only restores the column
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS code text;
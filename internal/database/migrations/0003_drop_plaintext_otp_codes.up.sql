@@ -0,0 +1,6 @@
+-- Deployments from before codes were hashed still have the plaintext code
+-- column. Its codes cannot be hashed here without the pepper, and expire
+-- within minutes anyway, so any still pending are invalidated: those users
+-- request a new code.
+UPDATE otps SET is_used = true WHERE code_hash = '';
+ALTER TABLE otps DROP COLUMN IF EXISTS code;
@@ -0,0 +1 @@
+ALTER TABLE sessions DROP COLUMN previous_refresh_token_hash;
@@ -0,0 +1,4 @@
+-- The hash of the refresh token a session was last rotated away from. Only
+-- that token coming back counts as reuse; other wrong tokens are refused
+-- without revoking the session.
+ALTER TABLE sessions ADD COLUMN previous_refresh_token_hash text NOT NULL DEFAULT '';
@@ -0,0 +1,68 @@
+-- Baseline schema, matching the Postgres migration of the same version. IDs
+-- are generated by the application, timestamps are stored as text.
+
+CREATE TABLE users (
+    id            text        NOT NULL,
+    phone_number  text        NOT NULL,
+    role          varchar(32) NOT NULL DEFAULT 'user',
+    token_version integer     NOT NULL DEFAULT 0,
+    created_at    datetime,
+    updated_at    datetime,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_users_phone_number ON users (phone_number);
+
+CREATE TABLE otps (
+    id              text     NOT NULL,
+    phone_number    text     NOT NULL,
+    code_hash       text     NOT NULL DEFAULT '',
+    created_at      datetime,
+    expires_at      datetime NOT NULL,
+    is_used         boolean  DEFAULT false,
+    failed_attempts integer  NOT NULL DEFAULT 0,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_otps_phone_number ON otps (phone_number);
+
+CREATE TABLE otp_attempts (
+    id           text NOT NULL,
+    phone_number text NOT NULL,
+    attempt_time datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_otp_attempts_phone_number ON otp_attempts (phone_number);
+
+CREATE TABLE otp_lockouts (
+    phone_number text     NOT NULL,
+    level        integer  NOT NULL DEFAULT 0,
+    locked_until datetime NOT NULL,
+    updated_at   datetime,
+    PRIMARY KEY (phone_number)
+);
+
+CREATE TABLE sessions (
+    id                 text         NOT NULL,
+    user_id            text         NOT NULL,
+    refresh_token_hash text         NOT NULL,
+    user_agent         varchar(512),
+    client_ip          varchar(64),
+    device_name        varchar(100),
+    last_seen_at       datetime,
+    expires_at         datetime     NOT NULL,
+    revoked_at         datetime,
+    created_at         datetime,
+    updated_at         datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+CREATE UNIQUE INDEX idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+
+CREATE TABLE revoked_tokens (
+    jti        text     NOT NULL,
+    user_id    text     NOT NULL,
+    expires_at datetime NOT NULL,
+    created_at datetime,
+    PRIMARY KEY (jti)
+);
+CREATE INDEX idx_revoked_tokens_user_id ON revoked_tokens (user_id);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE rate_limits (
+    key        text     NOT NULL,
+    count      real     NOT NULL DEFAULT 0,
+    previous   real     NOT NULL DEFAULT 0,
+    time       datetime NOT NULL,
+    expires_at datetime NOT NULL,
+    PRIMARY KEY (key)
+);
+CREATE INDEX idx_rate_limits_expires_at ON rate_limits (expires_at);
@@ -0,0 +1,2 @@
+-- Nothing to revert: the up migration changed no schema
+SELECT 1;
@@ -0,0 +1,3 @@
+-- SQLite databases never had the plaintext code column; this only keeps the
+-- versions in step with postgres and retires any unhashed code
+UPDATE otps SET is_used = 1 WHERE code_hash = '';
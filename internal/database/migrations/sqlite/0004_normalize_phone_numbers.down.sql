@@ -0,0 +1,3 @@
+-- Nothing to revert: which numbers were entered without their + is not
+-- kept, and the removed duplicate accounts cannot be restored
+SELECT 1;
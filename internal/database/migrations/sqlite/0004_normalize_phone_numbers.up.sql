@@ -0,0 +1,42 @@
+-- Phone numbers are stored in E.164 form with a leading +. Older releases
+-- stored them as entered, with or without it, so one number may have an
+-- account under each form. The oldest account of a number is kept; the
+-- others are removed together with their sessions.
+DELETE FROM sessions WHERE user_id IN (
+    SELECT id FROM (
+        SELECT id, row_number() OVER (
+            PARTITION BY '+' || ltrim(trim(phone_number), '+') ORDER BY created_at, id
+        ) AS n
+        FROM users
+    ) ranked
+    WHERE n > 1
+);
+DELETE FROM users WHERE id IN (
+    SELECT id FROM (
+        SELECT id, row_number() OVER (
+            PARTITION BY '+' || ltrim(trim(phone_number), '+') ORDER BY created_at, id
+        ) AS n
+        FROM users
+    ) ranked
+    WHERE n > 1
+);
+UPDATE users SET phone_number = '+' || ltrim(trim(phone_number), '+')
+WHERE phone_number <> '+' || ltrim(trim(phone_number), '+');
+
+-- A number locked out under both forms keeps the later lockout
+DELETE FROM otp_lockouts WHERE phone_number IN (
+    SELECT phone_number FROM (
+        SELECT phone_number, row_number() OVER (
+            PARTITION BY '+' || ltrim(trim(phone_number), '+') ORDER BY locked_until DESC, phone_number
+        ) AS n
+        FROM otp_lockouts
+    ) ranked
+    WHERE n > 1
+);
+UPDATE otp_lockouts SET phone_number = '+' || ltrim(trim(phone_number), '+')
+WHERE phone_number <> '+' || ltrim(trim(phone_number), '+');
+
+UPDATE otps SET phone_number = '+' || ltrim(trim(phone_number), '+')
+WHERE phone_number <> '+' || ltrim(trim(phone_number), '+');
+UPDATE otp_attempts SET phone_number = '+' || ltrim(trim(phone_number), '+')
+WHERE phone_number <> '+' || ltrim(trim(phone_number), '+');
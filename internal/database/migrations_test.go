@@ -0,0 +1,344 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go-auth/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_email.up.sql":        {Data: []byte("ALTER TABLE users ADD COLUMN email text;")},
+		"0002_add_email.down.sql":      {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+		"0001_initial.up.sql":          {Data: []byte("CREATE TABLE users (id uuid);")},
+		"0001_initial.down.sql":        {Data: []byte("DROP TABLE users;")},
+		"0010_backfill_email.up.sql":   {Data: []byte("UPDATE users SET email = '';")},
+		"0010_backfill_email.down.sql": {Data: []byte("-- nothing to revert")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "initial", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE users;", migrations[0].Down)
+	assert.Equal(t, 2, migrations[1].Version)
+	assert.Equal(t, 10, migrations[2].Version)
+}
+
+func TestLoadMigrationsRejectsBrokenSets(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "missing down file",
+			fsys: fstest.MapFS{"0001_initial.up.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name: "unexpected file",
+			fsys: fstest.MapFS{"initial.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name: "zero version",
+			fsys: fstest.MapFS{
+				"0000_initial.up.sql":   {Data: []byte("SELECT 1;")},
+				"0000_initial.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+		{
+			name: "one version with two names",
+			fsys: fstest.MapFS{
+				"0001_initial.up.sql": {Data: []byte("SELECT 1;")},
+				"0001_other.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMigrations(tt.fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := EmbeddedMigrations(DriverPostgres)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "versions must be contiguous")
+	}
+	assert.Equal(t, migrations[len(migrations)-1].Version, SchemaVersion())
+
+	sqliteMigrations, err := EmbeddedMigrations(DriverSQLite)
+	require.NoError(t, err)
+	require.Len(t, sqliteMigrations, len(migrations), "every migration needs a SQLite counterpart")
+	for i, migration := range sqliteMigrations {
+		assert.Equal(t, migrations[i].Version, migration.Version)
+		assert.Equal(t, migrations[i].Name, migration.Name)
+	}
+
+	_, err = EmbeddedMigrations("mysql")
+	assert.Error(t, err)
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	paths, err := CreateMigration(dir, "initial")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "0001_initial.up.sql"),
+		filepath.Join(dir, "0001_initial.down.sql"),
+	}, paths)
+
+	// With a sqlite directory both drivers get the next version
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sqlite"), 0o755))
+	paths, err = CreateMigration(dir, "add_email")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "0002_add_email.up.sql"),
+		filepath.Join(dir, "0002_add_email.down.sql"),
+		filepath.Join(dir, "sqlite", "0002_add_email.up.sql"),
+		filepath.Join(dir, "sqlite", "0002_add_email.down.sql"),
+	}, paths)
+
+	migrations, err := LoadMigrations(os.DirFS(dir))
+	require.NoError(t, err)
+	assert.Len(t, migrations, 2)
+
+	_, err = CreateMigration(dir, "Add-Email")
+	assert.Error(t, err)
+}
+
+func TestMigratorAgainstSQLite(t *testing.T) {
+	db, err := OpenSQLite(":memory:", &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	testMigrator(t, sqlDB, DriverSQLite)
+}
+
+// TestMigratorAgainstPostgres needs a disposable database, e.g.
+//
+// TEST_DATABASE_DSN="host=localhost user=postgres password=password dbname=go_auth_test sslmode=disable"
+func TestMigratorAgainstPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" || testing.Short() {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	testMigrator(t, sqlDB, DriverPostgres)
+}
+
+func TestNormalizePhoneNumbersAgainstSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenSQLite(":memory:", &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	migrations, err := EmbeddedMigrations(DriverSQLite)
+	require.NoError(t, err)
+	older, err := NewMigrator(sqlDB, DriverSQLite, migrations[:3])
+	require.NoError(t, err)
+	_, err = older.Up(ctx)
+	require.NoError(t, err)
+
+	// One number signed up without its + first and with it later, another
+	// only without it
+	require.NoError(t, db.Exec(`INSERT INTO users (id, phone_number, role, created_at) VALUES
+		('first', '15551234567', 'user', '2024-01-01 00:00:00'),
+		('second', '+15551234567', 'user', '2025-01-01 00:00:00'),
+		('other', ' 447700900123', 'user', '2024-01-01 00:00:00')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO sessions (id, user_id, refresh_token_hash, expires_at) VALUES
+		('kept', 'first', 'a', '2030-01-01 00:00:00'),
+		('dropped', 'second', 'b', '2030-01-01 00:00:00')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO otp_lockouts (phone_number, level, locked_until) VALUES
+		('15551234567', 1, '2025-01-01 00:00:00'),
+		('+15551234567', 2, '2025-01-02 00:00:00')`).Error)
+
+	migrator, err := NewMigrator(sqlDB, DriverSQLite, migrations)
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	var users []struct{ ID, PhoneNumber string }
+	require.NoError(t, db.Table("users").Order("phone_number").Find(&users).Error)
+	require.Len(t, users, 2)
+	assert.Equal(t, "first", users[0].ID, "the oldest account is kept")
+	assert.Equal(t, "+15551234567", users[0].PhoneNumber)
+	assert.Equal(t, "+447700900123", users[1].PhoneNumber)
+
+	var sessions []string
+	require.NoError(t, db.Table("sessions").Pluck("id", &sessions).Error)
+	assert.Equal(t, []string{"kept"}, sessions)
+
+	var lockouts []models.OTPLockout
+	require.NoError(t, db.Find(&lockouts).Error)
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, 2, lockouts[0].Level)
+}
+
+// The tables the first release created with AutoMigrate
+type baselineUser struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	PhoneNumber string    `gorm:"uniqueIndex;not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineOTP struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	PhoneNumber string    `gorm:"index;not null"`
+	Code        string    `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	IsUsed      bool      `gorm:"default:false"`
+}
+
+func (baselineOTP) TableName() string { return "otps" }
+
+type baselineOTPAttempt struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	PhoneNumber string    `gorm:"index;not null"`
+	AttemptTime time.Time `gorm:"autoCreateTime"`
+}
+
+func (baselineOTPAttempt) TableName() string { return "otp_attempts" }
+
+// TestMigrateUpFromBaselineAgainstPostgres upgrades a database created by
+// the first release. It runs in a schema of its own, so it needs a DSN in
+// key=value form.
+func TestMigrateUpFromBaselineAgainstPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" || testing.Short() {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+	ctx := context.Background()
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	require.NoError(t, err)
+	schema := fmt.Sprintf("baseline_%d", rand.Uint32())
+	require.NoError(t, admin.Exec("CREATE SCHEMA "+schema).Error)
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+schema), config)
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	require.NoError(t, db.AutoMigrate(&baselineUser{}, &baselineOTP{}, &baselineOTPAttempt{}))
+	require.NoError(t, db.Create(&baselineUser{PhoneNumber: "+15551234567"}).Error)
+	require.NoError(t, db.Create(&baselineOTP{PhoneNumber: "+15551234567", Code: "123456", ExpiresAt: time.Now().Add(time.Minute)}).Error)
+
+	migrations, err := EmbeddedMigrations(DriverPostgres)
+	require.NoError(t, err)
+	migrator, err := NewMigrator(sqlDB, DriverPostgres, migrations)
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	// Every column the models use is there
+	for _, model := range []interface{}{&models.User{}, &models.OTP{}, &models.OTPAttempt{},
+		&models.OTPLockout{}, &models.Session{}, &models.RevokedToken{}} {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" {
+				assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+	assert.False(t, db.Migrator().HasColumn(&models.OTP{}, "code"), "the plaintext code is dropped")
+
+	var user models.User
+	require.NoError(t, db.Where("phone_number = ?", "+15551234567").First(&user).Error)
+	assert.Equal(t, models.RoleUser, user.Role)
+	assert.Equal(t, 0, user.TokenVersion)
+
+	var otp models.OTP
+	require.NoError(t, db.Where("phone_number = ?", "+15551234567").First(&otp).Error)
+	assert.True(t, otp.IsUsed, "codes from before hashing are invalidated")
+}
+
+func testMigrator(t *testing.T, sqlDB *sql.DB, driver string) {
+	ctx := context.Background()
+	migrations, err := EmbeddedMigrations(driver)
+	require.NoError(t, err)
+	migrator, err := NewMigrator(sqlDB, driver, migrations)
+	require.NoError(t, err)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(migrations))
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	version, pending, err := migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), version)
+	assert.Empty(t, pending)
+
+	// Running again is a no-op
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, "migration %d", status.Version)
+	}
+
+	// A binary that knows fewer migrations refuses the database
+	older, err := NewMigrator(sqlDB, driver, migrations[:len(migrations)-1])
+	require.NoError(t, err)
+	_, _, err = older.Version(ctx)
+	assert.Error(t, err)
+
+	reverted, err := migrator.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, migrator.Latest(), reverted[0].Version)
+
+	_, pending, err = migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-auth/internal/config"
+
+	"gorm.io/gorm"
+)
+
+const deadlineKey = "timeouts:deadline"
+
+// statementDeadline remembers the context a statement had before its
+// deadline was applied, so a chain reused for a second query (e.g. Count
+// then Find) does not inherit the first one's cancelled context
+type statementDeadline struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+// ApplyQueryTimeouts bounds every statement run through db: reads by
+// ReadTimeout, inserts, updates, deletes and Exec by WriteTimeout. A shorter
+// deadline already on the statement's context still applies. Row and Rows
+// are not bounded because the caller reads them after the callbacks return.
+func ApplyQueryTimeouts(db *gorm.DB, cfg *config.DatabaseConfig) error {
+	callback := db.Callback()
+	read, write := withDeadline(cfg.ReadTimeout), withDeadline(cfg.WriteTimeout)
+
+	register := []struct {
+		operation string
+		before    error
+		after     error
+	}{
+		{"create", callback.Create().Before("gorm:create").Register("timeouts:before_create", write),
+			callback.Create().After("gorm:create").Register("timeouts:after_create", releaseDeadline)},
+		{"query", callback.Query().Before("gorm:query").Register("timeouts:before_query", read),
+			callback.Query().After("gorm:query").Register("timeouts:after_query", releaseDeadline)},
+		{"update", callback.Update().Before("gorm:update").Register("timeouts:before_update", write),
+			callback.Update().After("gorm:update").Register("timeouts:after_update", releaseDeadline)},
+		{"delete", callback.Delete().Before("gorm:delete").Register("timeouts:before_delete", write),
+			callback.Delete().After("gorm:delete").Register("timeouts:after_delete", releaseDeadline)},
+		{"raw", callback.Raw().Before("gorm:raw").Register("timeouts:before_raw", write),
+			callback.Raw().After("gorm:raw").Register("timeouts:after_raw", releaseDeadline)},
+	}
+
+	for _, r := range register {
+		if err := errors.Join(r.before, r.after); err != nil {
+			return fmt.Errorf("failed to register %s timeout callbacks: %w", r.operation, err)
+		}
+	}
+
+	return nil
+}
+
+func withDeadline(timeout time.Duration) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		db.Statement.Context = ctx
+		db.InstanceSet(deadlineKey, statementDeadline{parent: parent, cancel: cancel})
+	}
+}
+
+func releaseDeadline(db *gorm.DB) {
+	value, ok := db.InstanceGet(deadlineKey)
+	if !ok {
+		return
+	}
+	deadline, ok := value.(statementDeadline)
+	if !ok {
+		return
+	}
+
+	deadline.cancel()
+	db.Statement.Context = deadline.parent
+}
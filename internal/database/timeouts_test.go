@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+// dryRun builds statements without touching a database but still runs the
+// callbacks. observe sees each statement's context while it executes.
+func dryRun(t *testing.T, observe func(ctx context.Context)) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, ApplyQueryTimeouts(db, &config.DatabaseConfig{
+		ReadTimeout:  time.Second,
+		WriteTimeout: time.Minute,
+	}))
+
+	capture := func(db *gorm.DB) { observe(db.Statement.Context) }
+	require.NoError(t, db.Callback().Query().After("gorm:query").Before("timeouts:after_query").Register("test:query", capture))
+	require.NoError(t, db.Callback().Create().After("gorm:create").Before("timeouts:after_create").Register("test:create", capture))
+
+	return db
+}
+
+func remaining(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	return time.Until(deadline)
+}
+
+func TestApplyQueryTimeoutsByOperation(t *testing.T) {
+	var seen []time.Duration
+	db := dryRun(t, func(ctx context.Context) { seen = append(seen, remaining(ctx)) })
+
+	var found []widget
+	db.WithContext(context.Background()).Find(&found)
+	db.WithContext(context.Background()).Create(&widget{Name: "a"})
+
+	require.Len(t, seen, 2)
+	assert.InDelta(t, time.Second, seen[0], float64(100*time.Millisecond), "reads use the read timeout")
+	assert.InDelta(t, time.Minute, seen[1], float64(100*time.Millisecond), "writes use the write timeout")
+}
+
+func TestApplyQueryTimeoutsKeepsShorterDeadline(t *testing.T) {
+	var seen time.Duration
+	db := dryRun(t, func(ctx context.Context) { seen = remaining(ctx) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var found []widget
+	db.WithContext(ctx).Find(&found)
+
+	assert.LessOrEqual(t, seen, 100*time.Millisecond)
+}
+
+func TestApplyQueryTimeoutsRestoresContextForReusedChains(t *testing.T) {
+	var contexts []context.Context
+	var errs []error
+	db := dryRun(t, func(ctx context.Context) {
+		contexts = append(contexts, ctx)
+		errs = append(errs, ctx.Err())
+	})
+
+	var total int64
+	var found []widget
+	query := db.WithContext(context.Background()).Model(&widget{})
+	query.Count(&total)
+	query.Limit(10).Find(&found)
+
+	require.Len(t, contexts, 2)
+	assert.ErrorIs(t, contexts[0].Err(), context.Canceled, "the first deadline is released after its statement")
+	assert.NoError(t, errs[1], "the second statement must not inherit the released deadline")
+	assert.NoError(t, query.Statement.Context.Err())
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-auth/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler exposes operational state to administrators
+type AdminHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+// NewAdminHandler creates a new admin handler. scheduler may be nil when
+// background jobs are disabled.
+func NewAdminHandler(scheduler *scheduler.Scheduler) *AdminHandler {
+	return &AdminHandler{
+		scheduler: scheduler,
+	}
+}
+
+// GetJobs returns the background job stats of this replica
+// @Summary List background jobs
+// @Description Run counts, failures and leadership of the maintenance jobs on the serving replica
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} scheduler.JobStats
+// @Router /admin/jobs [get]
+func (h *AdminHandler) GetJobs(c *gin.Context) {
+	jobs := []scheduler.JobStats{}
+	if h.scheduler != nil {
+		jobs = h.scheduler.Stats()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"jobs":    jobs,
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"go-auth/internal/config"
 	"go-auth/internal/models"
@@ -11,15 +12,21 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const maxUserAgentLength = 512
+
 type AuthHandler struct {
-	otpService *services.OTPService
-	config     *config.Config
+	otpService   *services.OTPService
+	tokenService *services.TokenService
+	challenges   *services.ChallengeGate
+	config       *config.Config
 }
 
-func NewAuthHandler(otpService *services.OTPService, config *config.Config) *AuthHandler {
+func NewAuthHandler(otpService *services.OTPService, tokenService *services.TokenService, challenges *services.ChallengeGate, config *config.Config) *AuthHandler {
 	return &AuthHandler{
-		otpService: otpService,
-		config:     config,
+		otpService:   otpService,
+		tokenService: tokenService,
+		challenges:   challenges,
+		config:       config,
 	}
 }
 
@@ -27,8 +34,9 @@ func NewAuthHandler(otpService *services.OTPService, config *config.Config) *Aut
 // @Tags authentication
 // @Accept json
 // @Produce json
-// @Param request body models.SendOTPRequest true "Phone number"
+// @Param request body models.SendOTPRequest true "Phone number, and the solved challenge when one was asked for"
 // @Success 200 {object} models.SendOTPResponse
+// @Failure 428 {object} models.ErrorResponse "A challenge must be solved first"
 // @Router /auth/send-otp [post]
 func (h *AuthHandler) SendOTP(c *gin.Context) {
 	var req models.SendOTPRequest
@@ -42,7 +50,19 @@ func (h *AuthHandler) SendOTP(c *gin.Context) {
 		return
 	}
 
-	if err := h.otpService.SendOTP(req.PhoneNumber); err != nil {
+	challenge, err := h.challenges.Check(c.Request.Context(), req.PhoneNumber, c.ClientIP(), req.Challenge)
+	if err != nil {
+		appErr := utils.HandleError(err)
+		c.JSON(appErr.HTTPCode, models.ErrorResponse{
+			Success:   false,
+			Message:   "Failed to send OTP",
+			Error:     appErr.Message,
+			Challenge: challenge,
+		})
+		return
+	}
+
+	if err := h.otpService.SendOTP(c.Request.Context(), req.PhoneNumber, c.ClientIP()); err != nil {
 		appErr := utils.HandleError(err)
 		c.JSON(appErr.HTTPCode, models.ErrorResponse{
 			Success: false,
@@ -77,32 +97,160 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 		return
 	}
 
-	user, err := h.otpService.VerifyOTP(req.PhoneNumber, req.Code)
+	user, err := h.otpService.VerifyOTP(c.Request.Context(), req.PhoneNumber, req.Code)
 	if err != nil {
 		appErr := utils.HandleError(err)
+		if appErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(appErr.RetryAfter))
+		}
 		c.JSON(appErr.HTTPCode, models.ErrorResponse{
-			Success: false,
-			Message: "OTP verification failed",
-			Error:   appErr.Message,
+			Success:    false,
+			Message:    "OTP verification failed",
+			Error:      appErr.Message,
+			RetryAfter: appErr.RetryAfter,
 		})
 		return
 	}
 
-	token, err := utils.GenerateJWT(user.ID, user.PhoneNumber, h.config.JWT.Secret)
+	tokens, err := h.tokenService.IssueTokens(c.Request.Context(), user, sessionMetadata(c, req.DeviceName))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		appErr := utils.HandleError(err)
+		c.JSON(appErr.HTTPCode, models.ErrorResponse{
 			Success: false,
 			Message: "Failed to generate token",
-			Error:   err.Error(),
+			Error:   appErr.Message,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, models.VerifyOTPResponse{
-		Success: true,
-		Message: "Authentication successful",
-		Token:   token,
-		User:    user,
+		Success:      true,
+		Message:      "Authentication successful",
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
+	})
+}
+
+// @Summary Refresh access token
+// @Description Rotates the refresh token and issues a new access token. Reusing an old refresh token revokes the session.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} models.RefreshTokenResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	tokens, err := h.tokenService.Refresh(c.Request.Context(), req.RefreshToken, sessionMetadata(c, ""))
+	if err != nil {
+		appErr := utils.HandleError(err)
+		c.JSON(appErr.HTTPCode, models.ErrorResponse{
+			Success: false,
+			Message: "Failed to refresh token",
+			Error:   appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RefreshTokenResponse{
+		Success:      true,
+		Message:      "Token refreshed successfully",
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	})
+}
+
+// @Summary Logout
+// @Description Revokes the current access token and, if provided, the session of the refresh token
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.LogoutRequest false "Refresh token to revoke"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.LogoutRequest
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Success: false,
+				Message: "Invalid request format",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	claims, ok := c.MustGet("claims").(*utils.JWTClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Success: false,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	if err := h.tokenService.Logout(c.Request.Context(), claims, req.RefreshToken); err != nil {
+		appErr := utils.HandleError(err)
+		c.JSON(appErr.HTTPCode, models.ErrorResponse{
+			Success: false,
+			Message: "Failed to logout",
+			Error:   appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out successfully",
+	})
+}
+
+// @Summary Logout from all devices
+// @Description Revokes every access token and session of the current user
+// @Tags authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	claims, ok := c.MustGet("claims").(*utils.JWTClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Success: false,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	if err := h.tokenService.LogoutAll(c.Request.Context(), claims.UserID); err != nil {
+		appErr := utils.HandleError(err)
+		c.JSON(appErr.HTTPCode, models.ErrorResponse{
+			Success: false,
+			Message: "Failed to logout",
+			Error:   appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out from all devices",
 	})
 }
 
@@ -123,12 +271,29 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	}
 
 	phoneNumber, _ := c.Get("phone_number")
+	claims := c.MustGet("claims").(*utils.JWTClaims)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"user": gin.H{
 			"id":           userID,
 			"phone_number": phoneNumber,
+			"role":         claims.Role,
+			"permissions":  claims.Permissions,
 		},
 	})
 }
+
+// sessionMetadata describes the calling client for the session record
+func sessionMetadata(c *gin.Context, deviceName string) models.SessionMetadata {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	return models.SessionMetadata{
+		UserAgent:  userAgent,
+		ClientIP:   c.ClientIP(),
+		DeviceName: utils.SanitizeString(deviceName),
+	}
+}
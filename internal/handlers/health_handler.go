@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-auth/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler serves the Kubernetes liveness and readiness probes
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// Livez reports that the process is running. It checks no dependencies, so an
+// outage of Postgres or the SMS gateway never gets instances restarted.
+// @Summary Liveness probe
+// @Description Returns 200 while the process is able to serve HTTP
+// @Tags system
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": health.StatusUp,
+	})
+}
+
+// Readyz reports whether this instance can serve logins
+// @Summary Readiness probe
+// @Description Checks the database, SMS sender and JWT keyring; returns 503 when any of them is down
+// @Tags system
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.checker.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-auth/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public keys that verify access tokens
+type JWKSHandler struct {
+	tokenService *services.TokenService
+}
+
+// NewJWKSHandler creates a new JWKS handler
+func NewJWKSHandler(tokenService *services.TokenService) *JWKSHandler {
+	return &JWKSHandler{
+		tokenService: tokenService,
+	}
+}
+
+// GetJWKS returns the JSON Web Key Set
+// @Summary Get JSON Web Key Set
+// @Description Public keys for verifying access tokens, selected by the kid header
+// @Tags system
+// @Produce json
+// @Success 200 {object} utils.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokenService.JWKS())
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-auth/internal/models"
+	"go-auth/internal/services"
+	"go-auth/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SessionHandler struct {
+	tokenService *services.TokenService
+}
+
+func NewSessionHandler(tokenService *services.TokenService) *SessionHandler {
+	return &SessionHandler{
+		tokenService: tokenService,
+	}
+}
+
+// @Summary List active sessions
+// @Description Returns the devices the current user is signed in on
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.SessionResponse
+// @Router /auth/sessions [get]
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.JWTClaims)
+
+	sessions, err := h.tokenService.ListSessions(c.Request.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		appErr := utils.HandleError(err)
+		c.JSON(appErr.HTTPCode, models.ErrorResponse{
+			Success: false,
+			Message: "Failed to get sessions",
+			Error:   appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"sessions": sessions,
+	})
+}
+
+// @Summary Revoke a session
+// @Description Signs the given device out. Its access and refresh tokens stop working immediately.
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.JWTClaims)
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Message: "Invalid session ID format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := h.tokenService.RevokeSession(c.Request.Context(), claims.UserID, sessionID); err != nil {
+		appErr := utils.HandleError(err)
+		c.JSON(appErr.HTTPCode, models.ErrorResponse{
+			Success: false,
+			Message: "Failed to revoke session",
+			Error:   appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session revoked successfully",
+	})
+}
+
+// @Summary Revoke all other sessions
+// @Description Signs out every device except the one making the request
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/sessions [delete]
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.JWTClaims)
+
+	if err := h.tokenService.RevokeOtherSessions(c.Request.Context(), claims.UserID, claims.SessionID); err != nil {
+		appErr := utils.HandleError(err)
+		c.JSON(appErr.HTTPCode, models.ErrorResponse{
+			Success: false,
+			Message: "Failed to revoke sessions",
+			Error:   appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Other sessions revoked successfully",
+	})
+}
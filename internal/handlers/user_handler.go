@@ -42,7 +42,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(userID)
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
 		appErr := utils.HandleError(err)
 		c.JSON(appErr.HTTPCode, models.ErrorResponse{
@@ -56,6 +56,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	userResponse := models.UserResponse{
 		ID:          user.ID,
 		PhoneNumber: user.PhoneNumber,
+		Role:        user.Role,
 		CreatedAt:   user.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -93,7 +94,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 		limit = 100
 	}
 
-	response, err := h.userService.GetUsers(page, limit, search)
+	response, err := h.userService.GetUsers(c.Request.Context(), page, limit, search)
 	if err != nil {
 		appErr := utils.HandleError(err)
 		c.JSON(appErr.HTTPCode, models.ErrorResponse{
@@ -117,7 +118,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Router /users/stats [get]
 func (h *UserHandler) GetUserStats(c *gin.Context) {
-	stats, err := h.userService.GetUserStats()
+	stats, err := h.userService.GetUserStats(c.Request.Context())
 	if err != nil {
 		appErr := utils.HandleError(err)
 		c.JSON(appErr.HTTPCode, models.ErrorResponse{
@@ -133,3 +134,58 @@ func (h *UserHandler) GetUserStats(c *gin.Context) {
 		"stats":   stats,
 	})
 }
+
+// @Summary Change a user's role
+// @Description Requires the users:manage permission. The user's current access tokens are revoked.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.UpdateRoleRequest true "New role"
+// @Success 200 {object} models.UserResponse
+// @Router /users/{id}/role [put]
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.JWTClaims)
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Message: "Invalid user ID format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userService.UpdateRole(c.Request.Context(), claims.UserID, userID, req.Role)
+	if err != nil {
+		appErr := utils.HandleError(err)
+		c.JSON(appErr.HTTPCode, models.ErrorResponse{
+			Success: false,
+			Message: "Failed to update user role",
+			Error:   appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"user": models.UserResponse{
+			ID:          user.ID,
+			PhoneNumber: user.PhoneNumber,
+			Role:        user.Role,
+			CreatedAt:   user.CreatedAt.Format(time.RFC3339),
+		},
+	})
+}
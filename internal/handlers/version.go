@@ -64,6 +64,8 @@ func (h *VersionHandler) GetAPIInfo(c *gin.Context) {
 			"api_documentation":   "/swagger/index.html",
 			"endpoints": gin.H{
 				"health":  "/health",
+				"livez":   "/livez",
+				"readyz":  "/readyz",
 				"version": "/version",
 				"v1":      "/api/v1",
 			},
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-auth/internal/database"
+	"go-auth/internal/interfaces"
+	"go-auth/pkg/utils"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// DatabaseCheck pings Postgres and reports the pool usage and the schema
+// version the instance migrated to
+func DatabaseCheck(timeout time.Duration) Check {
+	return Check{
+		Name:    "database",
+		Timeout: timeout,
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			db := database.GetDB()
+			if db == nil {
+				return nil, errors.New("database not connected")
+			}
+
+			sqlDB, err := db.DB()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get database handle: %w", err)
+			}
+
+			stats := sqlDB.Stats()
+			details := map[string]interface{}{
+				"migration_version": database.MigrationVersion(),
+				"open_connections":  stats.OpenConnections,
+				"in_use":            stats.InUse,
+			}
+
+			if err := sqlDB.PingContext(ctx); err != nil {
+				return details, fmt.Errorf("failed to ping database: %w", err)
+			}
+
+			if version := database.MigrationVersion(); version != database.SchemaVersion() {
+				return details, fmt.Errorf("schema version %d, expected %d", version, database.SchemaVersion())
+			}
+
+			return details, nil
+		},
+	}
+}
+
+// RedisCheck pings the Redis server holding OTPs and attempts
+func RedisCheck(client goredis.UniversalClient, timeout time.Duration) Check {
+	return Check{
+		Name:    "redis",
+		Timeout: timeout,
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			stats := client.PoolStats()
+			details := map[string]interface{}{
+				"total_connections": stats.TotalConns,
+				"idle_connections":  stats.IdleConns,
+			}
+
+			if err := client.Ping(ctx).Err(); err != nil {
+				return details, fmt.Errorf("failed to ping redis: %w", err)
+			}
+
+			return details, nil
+		},
+	}
+}
+
+// SMSCheck probes the OTP sender when it supports health checks. Senders that
+// only deliver locally have nothing to probe and are always up.
+func SMSCheck(sender interfaces.OTPSender, provider string, timeout time.Duration) Check {
+	return Check{
+		Name:    "sms",
+		Timeout: timeout,
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			details := map[string]interface{}{"provider": provider}
+
+			checker, ok := sender.(interfaces.HealthChecker)
+			if !ok {
+				details["probed"] = false
+				return details, nil
+			}
+
+			details["probed"] = true
+			return details, checker.CheckHealth(ctx)
+		},
+	}
+}
+
+// KeyringCheck signs and verifies a short-lived token with the keyring, the
+// same way logins and authenticated requests use it
+func KeyringCheck(keyring *utils.Keyring, issuer string, timeout time.Duration) Check {
+	return Check{
+		Name:    "keyring",
+		Timeout: timeout,
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			active := keyring.Active()
+			if active == nil {
+				return nil, errors.New("no active signing key")
+			}
+
+			details := map[string]interface{}{
+				"active_kid": active.ID,
+				"algorithm":  active.Method.Alg(),
+			}
+
+			token, err := utils.GenerateJWT(utils.TokenSubject{UserID: uuid.Nil}, active, issuer, time.Minute)
+			if err != nil {
+				return details, fmt.Errorf("failed to sign with the active key: %w", err)
+			}
+
+			if _, err := utils.ValidateJWT(token, keyring, issuer); err != nil {
+				return details, fmt.Errorf("failed to verify with the active key: %w", err)
+			}
+
+			return details, nil
+		},
+	}
+}
@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check is a single readiness check. Run returns details to include in the
+// report; an error marks the check, and with it the instance, as down.
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Run     func(ctx context.Context) (map[string]interface{}, error)
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Status     string                 `json:"status"`
+	DurationMs int64                  `json:"duration_ms"`
+	Error      string                 `json:"error,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report is the outcome of all checks. Status is up only if every check is.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs readiness checks in parallel, each under its own timeout
+type Checker struct {
+	checks []Check
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := runCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// runCheck enforces the timeout even when a check ignores its context
+func runCheck(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	type outcome struct {
+		details map[string]interface{}
+		err     error
+	}
+	done := make(chan outcome, 1)
+
+	start := time.Now()
+	go func() {
+		details, err := check.Run(ctx)
+		done <- outcome{details: details, err: err}
+	}()
+
+	var result Result
+	select {
+	case out := <-done:
+		result.Details = out.details
+		if out.err != nil {
+			result.Status = StatusDown
+			result.Error = out.err.Error()
+		} else {
+			result.Status = StatusUp
+		}
+	case <-ctx.Done():
+		result.Status = StatusDown
+		result.Error = "timed out after " + check.Timeout.String()
+		if ctx.Err() == context.Canceled {
+			result.Error = "canceled"
+		}
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	return result
+}
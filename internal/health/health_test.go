@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-auth/pkg/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func staticCheck(name string, err error) Check {
+	return Check{
+		Name:    name,
+		Timeout: time.Second,
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			return map[string]interface{}{"name": name}, err
+		},
+	}
+}
+
+func TestCheckerReportsUpWhenAllChecksPass(t *testing.T) {
+	report := NewChecker(staticCheck("a", nil), staticCheck("b", nil)).Run(context.Background())
+
+	assert.Equal(t, StatusUp, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, StatusUp, report.Checks["a"].Status)
+	assert.Equal(t, "b", report.Checks["b"].Details["name"])
+}
+
+func TestCheckerReportsDownWhenAnyCheckFails(t *testing.T) {
+	report := NewChecker(staticCheck("a", nil), staticCheck("b", errors.New("connection refused"))).
+		Run(context.Background())
+
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUp, report.Checks["a"].Status)
+	assert.Equal(t, StatusDown, report.Checks["b"].Status)
+	assert.Equal(t, "connection refused", report.Checks["b"].Error)
+}
+
+func TestCheckerEnforcesPerCheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	// Ignores its context, as a stuck driver call would
+	hung := Check{
+		Name:    "hung",
+		Timeout: 20 * time.Millisecond,
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			<-release
+			return nil, nil
+		},
+	}
+
+	start := time.Now()
+	report := NewChecker(hung, staticCheck("fast", nil)).Run(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Contains(t, report.Checks["hung"].Error, "timed out")
+	assert.Equal(t, StatusUp, report.Checks["fast"].Status)
+}
+
+func TestDatabaseCheckWithoutConnection(t *testing.T) {
+	report := NewChecker(DatabaseCheck(time.Second)).Run(context.Background())
+
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, "database not connected", report.Checks["database"].Error)
+}
+
+type probedSender struct {
+	err error
+}
+
+func (s *probedSender) Send(ctx context.Context, phoneNumber, code string) error { return nil }
+func (s *probedSender) CheckHealth(ctx context.Context) error                    { return s.err }
+
+type localSender struct{}
+
+func (localSender) Send(ctx context.Context, phoneNumber, code string) error { return nil }
+
+func TestRedisCheck(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	up := NewChecker(RedisCheck(client, time.Second)).Run(context.Background())
+	assert.Equal(t, StatusUp, up.Status)
+
+	mr.Close()
+	down := NewChecker(RedisCheck(client, time.Second)).Run(context.Background())
+	assert.Equal(t, StatusDown, down.Status)
+}
+
+func TestSMSCheck(t *testing.T) {
+	up := NewChecker(SMSCheck(&probedSender{}, "http", time.Second)).Run(context.Background())
+	assert.Equal(t, StatusUp, up.Status)
+	assert.Equal(t, true, up.Checks["sms"].Details["probed"])
+
+	down := NewChecker(SMSCheck(&probedSender{err: errors.New("unreachable")}, "smpp", time.Second)).
+		Run(context.Background())
+	assert.Equal(t, StatusDown, down.Status)
+	assert.Equal(t, "smpp", down.Checks["sms"].Details["provider"])
+
+	local := NewChecker(SMSCheck(localSender{}, "console", time.Second)).Run(context.Background())
+	assert.Equal(t, StatusUp, local.Status)
+	assert.Equal(t, false, local.Checks["sms"].Details["probed"])
+}
+
+func TestKeyringCheck(t *testing.T) {
+	keyring := utils.NewKeyring(utils.NewHMACSigningKey("test-kid", "test-secret"))
+
+	report := NewChecker(KeyringCheck(keyring, "https://auth.example.com", time.Second)).Run(context.Background())
+
+	assert.Equal(t, StatusUp, report.Status)
+	assert.Equal(t, "test-kid", report.Checks["keyring"].Details["active_kid"])
+	assert.Equal(t, "HS256", report.Checks["keyring"].Details["algorithm"])
+}
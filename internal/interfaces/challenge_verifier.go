@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+
+	"go-auth/internal/models"
+)
+
+// ChallengeVerifier issues challenges, a CAPTCHA or a proof of work, and
+// checks their solutions
+type ChallengeVerifier interface {
+	// Issue describes a new challenge for the client to solve
+	Issue(ctx context.Context) (*models.Challenge, error)
+	// Verify reports whether solution solves a challenge. Wrong, expired
+	// and reused solutions are false; err is only set when the check itself
+	// failed.
+	Verify(ctx context.Context, solution, clientIP string) (bool, error)
+}
@@ -0,0 +1,9 @@
+package interfaces
+
+import "context"
+
+// HealthChecker is implemented by dependencies that can tell whether they are
+// currently usable, e.g. an SMS gateway that is reachable
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"context"
 	"time"
 
 	"go-auth/internal/models"
@@ -9,14 +10,22 @@ import (
 )
 
 type OTPRepository interface {
-	Create(otp *models.OTP) error
-	GetValidOTP(phoneNumber, code string) (*models.OTP, error)
-	MarkAsUsed(id uuid.UUID) error
-	DeleteExpired() error
+	Create(ctx context.Context, otp *models.OTP) error
+	GetPendingOTPs(ctx context.Context, phoneNumber string, limit int) ([]models.OTP, error)
+	Consume(ctx context.Context, id uuid.UUID) (bool, error)
+	RecordFailedAttempt(ctx context.Context, phoneNumber string, maxFailures int) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
 }
 
 type OTPAttemptRepository interface {
-	Create(attempt *models.OTPAttempt) error
-	CountRecentAttempts(phoneNumber string, since time.Time) (int64, error)
-	DeleteOldAttempts(before time.Time) error
+	Create(ctx context.Context, attempt *models.OTPAttempt) error
+	CountRecentAttempts(ctx context.Context, phoneNumber string, since time.Time) (int64, error)
+	DeleteOldAttempts(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OTPLockoutRepository interface {
+	Get(ctx context.Context, phoneNumber string) (*models.OTPLockout, error)
+	Save(ctx context.Context, lockout *models.OTPLockout) error
+	Delete(ctx context.Context, phoneNumber string) error
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
 }
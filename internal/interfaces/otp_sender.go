@@ -0,0 +1,7 @@
+package interfaces
+
+import "context"
+
+type OTPSender interface {
+	Send(ctx context.Context, phoneNumber, code string) error
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"go-auth/pkg/ratelimit"
+)
+
+// RateLimitRepository keeps rate-limiter state where every replica sees it
+type RateLimitRepository interface {
+	ratelimit.Store
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
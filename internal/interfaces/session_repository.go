@@ -0,0 +1,28 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"go-auth/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Session, error)
+	ListActiveForUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	Rotate(ctx context.Context, id uuid.UUID, currentHash, newHash string, expiresAt time.Time, metadata models.SessionMetadata) (bool, error)
+	Touch(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error
+	Revoke(ctx context.Context, id uuid.UUID) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+	RevokeAllForUserExcept(ctx context.Context, userID, keepID uuid.UUID) error
+	DeleteInactive(ctx context.Context, before time.Time) (int64, error)
+}
+
+type RevokedTokenRepository interface {
+	Create(ctx context.Context, token *models.RevokedToken) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
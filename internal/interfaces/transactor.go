@@ -0,0 +1,16 @@
+package interfaces
+
+import "context"
+
+// TxRepositories are repositories bound to a single database transaction
+type TxRepositories struct {
+	OTPs        OTPRepository
+	OTPLockouts OTPLockoutRepository
+	Users       UserRepository
+}
+
+// Transactor runs fn in a transaction that is committed when fn returns nil
+// and rolled back otherwise
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(repos TxRepositories) error) error
+}
@@ -1,16 +1,20 @@
 package interfaces
 
 import (
+	"context"
 	"go-auth/internal/models"
 
 	"github.com/google/uuid"
 )
 
 type UserRepository interface {
-	Create(user *models.User) error
-	GetByID(id uuid.UUID) (*models.User, error)
-	GetByPhoneNumber(phoneNumber string) (*models.User, error)
-	GetUsers(page, limit int, search string) ([]models.User, int64, error)
-	Update(user *models.User) error
-	Delete(id uuid.UUID) error
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByPhoneNumber(ctx context.Context, phoneNumber string) (*models.User, error)
+	FindOrCreateByPhoneNumber(ctx context.Context, phoneNumber string) (user *models.User, created bool, err error)
+	GetUsers(ctx context.Context, page, limit int, search string) ([]models.User, int64, error)
+	Update(ctx context.Context, user *models.User) error
+	UpdateRole(ctx context.Context, id uuid.UUID, role models.Role) error
+	IncrementTokenVersion(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID) error
 }
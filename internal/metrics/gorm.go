@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startTimeKey = "metrics:start_time"
+
+// InstrumentGORM records the latency of every query run through db, labelled
+// by operation and table. A missing record is not counted as an error.
+func InstrumentGORM(db *gorm.DB) error {
+	callback := db.Callback()
+
+	register := []struct {
+		operation string
+		before    error
+		after     error
+	}{
+		{"create", callback.Create().Before("gorm:create").Register("metrics:before_create", before),
+			callback.Create().After("gorm:create").Register("metrics:after_create", after("create"))},
+		{"query", callback.Query().Before("gorm:query").Register("metrics:before_query", before),
+			callback.Query().After("gorm:query").Register("metrics:after_query", after("query"))},
+		{"update", callback.Update().Before("gorm:update").Register("metrics:before_update", before),
+			callback.Update().After("gorm:update").Register("metrics:after_update", after("update"))},
+		{"delete", callback.Delete().Before("gorm:delete").Register("metrics:before_delete", before),
+			callback.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete"))},
+		{"row", callback.Row().Before("gorm:row").Register("metrics:before_row", before),
+			callback.Row().After("gorm:row").Register("metrics:after_row", after("row"))},
+		{"raw", callback.Raw().Before("gorm:raw").Register("metrics:before_raw", before),
+			callback.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw"))},
+	}
+
+	for _, r := range register {
+		if err := errors.Join(r.before, r.after); err != nil {
+			return fmt.Errorf("failed to register %s metrics callbacks: %w", r.operation, err)
+		}
+	}
+
+	return nil
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+func after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		success := db.Error == nil || errors.Is(db.Error, gorm.ErrRecordNotFound)
+		ObserveDBQuery(operation, table, success, time.Since(start))
+	}
+}
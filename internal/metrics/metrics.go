@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "goauth"
+
+// Outcomes of SendOTP
+const (
+	SendSent           = "sent"
+	SendInvalidPhone   = "invalid_phone"
+	SendRateLimited    = "rate_limited"
+	SendBlocked        = "blocked"
+	SendSuspended      = "suspended"
+	SendDeliveryFailed = "delivery_failed"
+	SendError          = "error"
+
+	// Refused by the challenge gate before reaching SendOTP
+	SendChallengeRequired    = "challenge_required"
+	SendChallengeFailed      = "challenge_failed"
+	SendChallengeUnavailable = "challenge_unavailable"
+)
+
+// Outcomes of VerifyOTP
+const (
+	VerifySuccess      = "success"
+	VerifyInvalidInput = "invalid_input"
+	VerifyLocked       = "locked"
+	VerifyWrongCode    = "wrong_code"
+	VerifyLockedOut    = "locked_out"
+	VerifyExpired      = "expired"
+	VerifyAlreadyUsed  = "already_used"
+	VerifyError        = "error"
+)
+
+// Registry holds the service's collectors together with the Go runtime and
+// process collectors
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	otpSends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "otp_send_total",
+		Help:      "OTP send requests by outcome.",
+	}, []string{"outcome"})
+
+	otpDelivery = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "otp_delivery_duration_seconds",
+		Help:      "Time taken by the SMS provider to accept an OTP, by provider and status.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"provider", "status"})
+
+	otpVerifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "otp_verify_total",
+		Help:      "OTP verification attempts by outcome.",
+	}, []string{"outcome"})
+
+	otpRateLimited = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "otp_rate_limited_total",
+		Help:      "OTP requests refused by the per phone number rate limit.",
+	})
+
+	dbQueries = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by operation, table and status.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "table", "status"})
+
+	jobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Background job runs on this replica by job.",
+	}, []string{"job"})
+
+	jobFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_failures_total",
+		Help:      "Background job runs that failed, by job.",
+	}, []string{"job"})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Background job run time by job.",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"job"})
+
+	jobLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_success_timestamp_seconds",
+		Help:      "Unix time the job last completed successfully on this replica.",
+	}, []string{"job"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		otpSends,
+		otpDelivery,
+		otpVerifications,
+		otpRateLimited,
+		dbQueries,
+		jobRuns,
+		jobFailures,
+		jobDuration,
+		jobLastSuccess,
+	)
+
+	// Start every outcome at zero so rate() and absent() alerts work before
+	// the first occurrence
+	for _, outcome := range []string{SendSent, SendInvalidPhone, SendRateLimited, SendBlocked, SendSuspended,
+		SendDeliveryFailed, SendError, SendChallengeRequired, SendChallengeFailed,
+		SendChallengeUnavailable} {
+		otpSends.WithLabelValues(outcome)
+	}
+	for _, outcome := range []string{VerifySuccess, VerifyInvalidInput, VerifyLocked, VerifyWrongCode,
+		VerifyLockedOut, VerifyExpired, VerifyAlreadyUsed, VerifyError} {
+		otpVerifications.WithLabelValues(outcome)
+	}
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveHTTPRequest records a served request. route is the route pattern,
+// not the raw path, so that IDs in URLs do not create new series.
+func ObserveHTTPRequest(route, method string, status int, latency time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, statusLabel).Inc()
+	httpDuration.WithLabelValues(route, method, statusLabel).Observe(latency.Seconds())
+}
+
+func ObserveOTPSend(outcome string) {
+	otpSends.WithLabelValues(outcome).Inc()
+}
+
+func ObserveOTPDelivery(provider string, success bool, latency time.Duration) {
+	otpDelivery.WithLabelValues(provider, status(success)).Observe(latency.Seconds())
+}
+
+func ObserveOTPVerification(outcome string) {
+	otpVerifications.WithLabelValues(outcome).Inc()
+}
+
+func ObserveOTPRateLimited() {
+	otpRateLimited.Inc()
+}
+
+func ObserveDBQuery(operation, table string, success bool, latency time.Duration) {
+	dbQueries.WithLabelValues(operation, table, status(success)).Observe(latency.Seconds())
+}
+
+// RegisterJob starts the job's counters at zero, so that a job that never
+// runs on this replica still reports
+func RegisterJob(job string) {
+	jobRuns.WithLabelValues(job)
+	jobFailures.WithLabelValues(job)
+}
+
+// ObserveJobRun records a background job run that ended at finishedAt
+func ObserveJobRun(job string, success bool, duration time.Duration, finishedAt time.Time) {
+	jobRuns.WithLabelValues(job).Inc()
+	jobDuration.WithLabelValues(job).Observe(duration.Seconds())
+
+	if !success {
+		jobFailures.WithLabelValues(job).Inc()
+		return
+	}
+	jobLastSuccess.WithLabelValues(job).Set(float64(finishedAt.Unix()))
+}
+
+func status(success bool) string {
+	if success {
+		return "ok"
+	}
+	return "error"
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sample returns a counter's or gauge's value or a histogram's sample count
+// from the registry, or 0 when the series does not exist yet
+func sample(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := Registry.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if want, ok := labels[label.GetName()]; ok && want != label.GetValue() {
+					continue metrics
+				}
+			}
+
+			if histogram := metric.GetHistogram(); histogram != nil {
+				return float64(histogram.GetSampleCount())
+			}
+			if gauge := metric.GetGauge(); gauge != nil {
+				return gauge.GetValue()
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestObserveHTTPRequest(t *testing.T) {
+	labels := map[string]string{"route": "/api/v1/users/:id", "method": "GET", "status": "404"}
+	requests := sample(t, "goauth_http_requests_total", labels)
+	observed := sample(t, "goauth_http_request_duration_seconds", labels)
+
+	ObserveHTTPRequest("/api/v1/users/:id", "GET", http.StatusNotFound, 15*time.Millisecond)
+
+	assert.Equal(t, requests+1, sample(t, "goauth_http_requests_total", labels))
+	assert.Equal(t, observed+1, sample(t, "goauth_http_request_duration_seconds", labels))
+}
+
+func TestObserveJobRun(t *testing.T) {
+	labels := map[string]string{"job": "test_job"}
+	RegisterJob("test_job")
+	assert.Equal(t, 0.0, sample(t, "goauth_job_runs_total", labels))
+
+	finished := time.Unix(1735732800, 0)
+	ObserveJobRun("test_job", true, time.Second, finished)
+	ObserveJobRun("test_job", false, 2*time.Second, finished.Add(time.Minute))
+
+	assert.Equal(t, 2.0, sample(t, "goauth_job_runs_total", labels))
+	assert.Equal(t, 1.0, sample(t, "goauth_job_failures_total", labels))
+	assert.Equal(t, 2.0, sample(t, "goauth_job_duration_seconds", labels))
+	assert.Equal(t, float64(finished.Unix()), sample(t, "goauth_job_last_success_timestamp_seconds", labels),
+		"failed runs leave the last success alone")
+}
+
+func TestObserveOTPOutcomes(t *testing.T) {
+	sent := sample(t, "goauth_otp_send_total", map[string]string{"outcome": SendSent})
+	limited := sample(t, "goauth_otp_rate_limited_total", nil)
+	wrong := sample(t, "goauth_otp_verify_total", map[string]string{"outcome": VerifyWrongCode})
+
+	ObserveOTPSend(SendSent)
+	ObserveOTPRateLimited()
+	ObserveOTPVerification(VerifyWrongCode)
+
+	assert.Equal(t, sent+1, sample(t, "goauth_otp_send_total", map[string]string{"outcome": SendSent}))
+	assert.Equal(t, limited+1, sample(t, "goauth_otp_rate_limited_total", nil))
+	assert.Equal(t, wrong+1, sample(t, "goauth_otp_verify_total", map[string]string{"outcome": VerifyWrongCode}))
+}
+
+func TestHandlerExposesMetrics(t *testing.T) {
+	ObserveOTPSend(SendDeliveryFailed)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `goauth_otp_send_total{outcome="delivery_failed"}`)
+	assert.Contains(t, recorder.Body.String(), "go_goroutines")
+}
+
+func TestInstrumentGORM(t *testing.T) {
+	// DryRun builds statements without touching a database but still runs
+	// the callbacks
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, InstrumentGORM(db))
+
+	type widget struct {
+		ID   uint
+		Name string
+	}
+
+	query := map[string]string{"operation": "query", "table": "widgets", "status": "ok"}
+	create := map[string]string{"operation": "create", "table": "widgets", "status": "ok"}
+	queries := sample(t, "goauth_db_query_duration_seconds", query)
+	creates := sample(t, "goauth_db_query_duration_seconds", create)
+
+	var found []widget
+	db.Where("name = ?", "a").Find(&found)
+	db.Create(&widget{Name: "b"})
+
+	assert.Equal(t, queries+1, sample(t, "goauth_db_query_duration_seconds", query))
+	assert.Equal(t, creates+1, sample(t, "goauth_db_query_duration_seconds", create))
+}
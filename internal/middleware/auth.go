@@ -1,17 +1,18 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
-	"go-auth/internal/config"
 	"go-auth/internal/models"
+	"go-auth/internal/services"
 	"go-auth/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+func AuthMiddleware(tokenService *services.TokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -45,12 +46,13 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := utils.ValidateJWT(token, cfg.JWT.Secret)
+		claims, err := tokenService.ValidateAccessToken(c.Request.Context(), token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			appErr := utils.HandleError(err)
+			c.JSON(appErr.HTTPCode, models.ErrorResponse{
 				Success: false,
 				Message: "Invalid or expired token",
-				Error:   err.Error(),
+				Error:   appErr.Message,
 			})
 			c.Abort()
 			return
@@ -59,27 +61,37 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("phone_number", claims.PhoneNumber)
 		c.Set("claims", claims)
+		c.Request = c.Request.WithContext(withUserLogFields(c.Request.Context(), claims))
 
 		c.Next()
 	}
 }
 
-func OptionalAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+func OptionalAuthMiddleware(tokenService *services.TokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
 		if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
 			token := strings.TrimPrefix(authHeader, "Bearer ")
 
-			if claims, err := utils.ValidateJWT(token, cfg.JWT.Secret); err == nil {
+			if claims, err := tokenService.ValidateAccessToken(c.Request.Context(), token); err == nil {
 				c.Set("user_id", claims.UserID)
 				c.Set("phone_number", claims.PhoneNumber)
 				c.Set("claims", claims)
 				c.Set("authenticated", true)
+				c.Request = c.Request.WithContext(withUserLogFields(c.Request.Context(), claims))
 			}
 		}
 
 		c.Next()
 	}
 }
- 
\ No newline at end of file
+
+// withUserLogFields tags the rest of the request's log lines with the
+// authenticated user and session
+func withUserLogFields(ctx context.Context, claims *utils.JWTClaims) context.Context {
+	return utils.WithLogFields(ctx, map[string]interface{}{
+		"user_id":    claims.UserID.String(),
+		"session_id": claims.SessionID.String(),
+	})
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"time"
 
+	"go-auth/internal/metrics"
 	"go-auth/pkg/utils"
 
 	"github.com/gin-gonic/gin"
@@ -28,7 +29,14 @@ func LoggingMiddleware() gin.HandlerFunc {
 		userAgent := c.Request.UserAgent()
 
 		// Log the request
-		utils.LogRequest(method, path, userAgent, clientIP, statusCode, latency)
+		utils.LogRequest(c.Request.Context(), method, path, userAgent, clientIP, statusCode, latency)
+
+		// Label by route pattern so IDs in paths do not create new series
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(route, method, statusCode, latency)
 	}
 }
 
@@ -43,6 +51,9 @@ func RequestIDMiddleware() gin.HandlerFunc {
 
 		c.Header("X-Request-ID", requestID)
 		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(utils.WithLogFields(c.Request.Context(), map[string]interface{}{
+			"request_id": requestID,
+		}))
 		c.Next()
 	}
 }
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-auth/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{}
+
+func (failingStore) Update(context.Context, string, time.Duration, func(ratelimit.State) ratelimit.State) error {
+	return errors.New("connection refused")
+}
+
+func serveLimited(t *testing.T, router *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodPost, "/send-otp", nil)
+	request.RemoteAddr = remoteAddr
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func limitedRouter(handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/send-otp", append(handlers, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})...)
+	return router
+}
+
+func TestRateLimitByClientIP(t *testing.T) {
+	limiter := ratelimit.NewSlidingWindow(ratelimit.NewMemoryStore(), "send-otp", 2, time.Hour)
+	router := limitedRouter(RateLimit(limiter, ClientIPKey))
+
+	first := serveLimited(t, router, "192.0.2.1:1234")
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, first.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusOK, serveLimited(t, router, "192.0.2.1:1234").Code)
+
+	denied := serveLimited(t, router, "192.0.2.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, denied.Code)
+	assert.Equal(t, "0", denied.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, denied.Header().Get("Retry-After"))
+	assert.Contains(t, denied.Body.String(), "RATE_LIMIT_EXCEEDED")
+
+	assert.Equal(t, http.StatusOK, serveLimited(t, router, "192.0.2.2:1234").Code, "other clients are unaffected")
+}
+
+func TestRateLimitReportsTheTightestLimit(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	router := limitedRouter(
+		RateLimit(ratelimit.NewTokenBucket(store, "ip", 100, time.Minute), ClientIPKey),
+		RateLimit(ratelimit.NewSlidingWindow(store, "send-otp", 5, time.Hour), ClientIPKey),
+	)
+
+	recorder := serveLimited(t, router, "192.0.2.1:1234")
+	assert.Equal(t, "5", recorder.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "4", recorder.Header().Get("RateLimit-Remaining"))
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	limiter := ratelimit.NewTokenBucket(ratelimit.NewMemoryStore(), "user", 1, time.Hour)
+	userID := uuid.New()
+	authenticated := func(c *gin.Context) {
+		c.Set("user_id", userID)
+	}
+
+	router := limitedRouter(authenticated, RateLimit(limiter, UserKey))
+	assert.Equal(t, http.StatusOK, serveLimited(t, router, "192.0.2.1:1234").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveLimited(t, router, "192.0.2.2:1234").Code,
+		"the limit follows the user across addresses")
+
+	anonymous := limitedRouter(RateLimit(limiter, UserKey))
+	assert.Equal(t, http.StatusOK, serveLimited(t, anonymous, "192.0.2.1:1234").Code)
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	limiter := ratelimit.NewSlidingWindow(failingStore{}, "send-otp", 1, time.Hour)
+	router := limitedRouter(RateLimit(limiter, ClientIPKey))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serveLimited(t, router, "192.0.2.1:1234").Code)
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"go-auth/internal/models"
+	"go-auth/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole allows the request through only if the access token was issued
+// for one of the given roles. It must run after AuthMiddleware.
+func RequireRole(roles ...models.Role) gin.HandlerFunc {
+	allowed := make([]string, len(roles))
+	for i, role := range roles {
+		allowed[i] = string(role)
+	}
+
+	return func(c *gin.Context) {
+		claims, ok := claimsFromContext(c)
+		if !ok || !claims.HasRole(allowed...) {
+			forbid(c, claims)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission allows the request through only if the access token
+// grants every given permission. It must run after AuthMiddleware.
+func RequirePermission(permissions ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := claimsFromContext(c)
+		if !ok {
+			forbid(c, nil)
+			return
+		}
+
+		for _, permission := range permissions {
+			if !claims.HasPermission(string(permission)) {
+				forbid(c, claims)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func claimsFromContext(c *gin.Context) (*utils.JWTClaims, bool) {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+
+	claims, ok := value.(*utils.JWTClaims)
+	return claims, ok
+}
+
+func forbid(c *gin.Context, claims *utils.JWTClaims) {
+	userID := ""
+	if claims != nil {
+		userID = claims.UserID.String()
+	}
+	utils.LogSecurityEvent(c.Request.Context(), "access_denied", userID, "", c.Request.Method+" "+c.FullPath())
+
+	appErr := utils.ErrForbidden
+	c.JSON(appErr.HTTPCode, models.ErrorResponse{
+		Success: false,
+		Message: appErr.Message,
+		Error:   appErr.Code,
+	})
+	c.Abort()
+}
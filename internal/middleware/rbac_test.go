@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-auth/internal/models"
+	"go-auth/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogger()
+	utils.Logger.SetLevel(logrus.PanicLevel)
+	m.Run()
+}
+
+func serveWithClaims(claims *utils.JWTClaims, guard gin.HandlerFunc) int {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users", func(c *gin.Context) {
+		if claims != nil {
+			c.Set("claims", claims)
+		}
+		c.Next()
+	}, guard, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users", nil))
+	return recorder.Code
+}
+
+func claimsFor(role models.Role) *utils.JWTClaims {
+	claims := &utils.JWTClaims{UserID: uuid.New(), Role: string(role)}
+	for _, permission := range role.Permissions() {
+		claims.Permissions = append(claims.Permissions, string(permission))
+	}
+	return claims
+}
+
+func TestRequireRole(t *testing.T) {
+	guard := RequireRole(models.RoleAdmin, models.RoleSupport)
+
+	assert.Equal(t, http.StatusOK, serveWithClaims(claimsFor(models.RoleAdmin), guard))
+	assert.Equal(t, http.StatusOK, serveWithClaims(claimsFor(models.RoleSupport), guard))
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(claimsFor(models.RoleUser), guard))
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(&utils.JWTClaims{UserID: uuid.New()}, guard),
+		"tokens issued before roles existed carry none")
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(nil, guard))
+}
+
+func TestRequirePermission(t *testing.T) {
+	read := RequirePermission(models.PermissionUsersRead)
+	manage := RequirePermission(models.PermissionUsersManage)
+
+	assert.Equal(t, http.StatusOK, serveWithClaims(claimsFor(models.RoleSupport), read))
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(claimsFor(models.RoleSupport), manage))
+	assert.Equal(t, http.StatusOK, serveWithClaims(claimsFor(models.RoleAdmin), manage))
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(claimsFor(models.RoleUser), read))
+}
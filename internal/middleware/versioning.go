@@ -34,7 +34,7 @@ func APIVersionMiddleware() gin.HandlerFunc {
 
 		// Validate version
 		if !isValidVersion(version) {
-			utils.LogSecurityEvent("invalid_api_version", "", "", "Invalid API version requested: "+version)
+			utils.LogSecurityEvent(c.Request.Context(), "invalid_api_version", "", "", "Invalid API version requested: "+version)
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
 				Success: false,
 				Message: "Invalid API version",
@@ -4,11 +4,28 @@ import "github.com/google/uuid"
 
 type SendOTPRequest struct {
 	PhoneNumber string `json:"phone_number" binding:"required" validate:"required"`
+	// Challenge is the solution to the challenge of an earlier response,
+	// required once the client or destination sends too many requests
+	Challenge string `json:"challenge,omitempty" binding:"max=4096"`
+}
+
+// Challenge is what a client must solve before send-otp accepts its request.
+// A pow challenge is solved by finding a counter for which the SHA-256 of
+// "<token>:<counter>" starts with Difficulty zero bits, and answered with
+// that string. A captcha is solved with the provider's widget for SiteKey,
+// and answered with the response token the widget returns.
+type Challenge struct {
+	Type       string `json:"type"`
+	Token      string `json:"token,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+	SiteKey    string `json:"site_key,omitempty"`
 }
 
 type VerifyOTPRequest struct {
 	PhoneNumber string `json:"phone_number" binding:"required" validate:"required"`
 	Code        string `json:"code" binding:"required" validate:"required"`
+	DeviceName  string `json:"device_name,omitempty" binding:"max=100"`
 }
 
 type SendOTPResponse struct {
@@ -17,18 +34,47 @@ type SendOTPResponse struct {
 }
 
 type VerifyOTPResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Token   string `json:"token,omitempty"`
-	User    *User  `json:"user,omitempty"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	User         *User  `json:"user,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" validate:"required"`
+}
+
+type RefreshTokenResponse struct {
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
 }
 
 type UserResponse struct {
 	ID          uuid.UUID `json:"id"`
 	PhoneNumber string    `json:"phone_number"`
+	Role        Role      `json:"role"`
 	CreatedAt   string    `json:"created_at"`
 }
 
+type UpdateRoleRequest struct {
+	Role Role `json:"role" binding:"required" validate:"required"`
+}
+
 type UsersListResponse struct {
 	Users      []UserResponse `json:"users"`
 	Total      int64          `json:"total"`
@@ -37,8 +83,21 @@ type UsersListResponse struct {
 	TotalPages int            `json:"total_pages"`
 }
 
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	DeviceName string    `json:"device_name,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	CreatedAt  string    `json:"created_at"`
+	LastSeenAt string    `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
+	Success    bool   `json:"success"`
+	Message    string `json:"message"`
+	Error      string `json:"error,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+	// Challenge is set when the request must be repeated with its solution
+	Challenge *Challenge `json:"challenge,omitempty"`
 }
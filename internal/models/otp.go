@@ -4,15 +4,25 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type OTP struct {
-	ID          uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	PhoneNumber string    `json:"phone_number" gorm:"index;not null"`
-	Code        string    `json:"code" gorm:"not null"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	ExpiresAt   time.Time `json:"expires_at" gorm:"not null"`
-	IsUsed      bool      `json:"is_used" gorm:"default:false"`
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	PhoneNumber    string    `json:"phone_number" gorm:"index;not null"`
+	CodeHash       string    `json:"-" gorm:"not null;default:''"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	ExpiresAt      time.Time `json:"expires_at" gorm:"not null"`
+	IsUsed         bool      `json:"is_used" gorm:"default:false"`
+	FailedAttempts int       `json:"-" gorm:"not null;default:0"`
+}
+
+// BeforeCreate assigns the ID in Go, so drivers without gen_random_uuid work
+func (o *OTP) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
 }
 
 func (o *OTP) IsExpired() bool {
@@ -29,6 +39,30 @@ type OTPAttempt struct {
 	AttemptTime time.Time `json:"attempt_time" gorm:"autoCreateTime"`
 }
 
+func (a *OTPAttempt) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
 func (OTPAttempt) TableName() string {
 	return "otp_attempts"
 }
+
+// OTPLockout blocks verification for a phone number after codes were
+// invalidated by repeated wrong guesses. Level drives the exponential backoff.
+type OTPLockout struct {
+	PhoneNumber string    `json:"phone_number" gorm:"primaryKey"`
+	Level       int       `json:"level" gorm:"not null;default:0"`
+	LockedUntil time.Time `json:"locked_until" gorm:"not null"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (l *OTPLockout) IsLocked() bool {
+	return time.Now().Before(l.LockedUntil)
+}
+
+func (OTPLockout) TableName() string {
+	return "otp_lockouts"
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RateLimit is the state of one rate-limit key, see pkg/ratelimit. Rows past
+// ExpiresAt count as absent.
+type RateLimit struct {
+	Key       string    `gorm:"primaryKey"`
+	Count     float64   `gorm:"not null;default:0"`
+	Previous  float64   `gorm:"not null;default:0"`
+	Time      time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+func (RateLimit) TableName() string {
+	return "rate_limits"
+}
@@ -0,0 +1,34 @@
+package models
+
+type Role string
+
+const (
+	RoleUser    Role = "user"
+	RoleSupport Role = "support"
+	RoleAdmin   Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermissionUsersRead   Permission = "users:read"
+	PermissionUsersManage Permission = "users:manage"
+)
+
+// rolePermissions lists what each role may do. End users have no
+// permissions beyond their own account.
+var rolePermissions = map[Role][]Permission{
+	RoleUser:    {},
+	RoleSupport: {PermissionUsersRead},
+	RoleAdmin:   {PermissionUsersRead, PermissionUsersManage},
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions returns the permissions granted to the role
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session is a refresh token family. The hashes of the current refresh token
+// and of the one it replaced are kept; presenting the replaced one again
+// means it leaked, and revokes the family.
+type Session struct {
+	ID                       uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID                   uuid.UUID  `json:"user_id" gorm:"type:uuid;index;not null"`
+	RefreshTokenHash         string     `json:"-" gorm:"uniqueIndex;not null"`
+	PreviousRefreshTokenHash string     `json:"-" gorm:"not null;default:''"`
+	UserAgent                string     `json:"user_agent" gorm:"size:512"`
+	ClientIP                 string     `json:"client_ip" gorm:"size:64"`
+	DeviceName               string     `json:"device_name" gorm:"size:100"`
+	LastSeenAt               time.Time  `json:"last_seen_at"`
+	ExpiresAt                time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt                *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt                time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt                time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// SessionMetadata describes the client a session belongs to
+type SessionMetadata struct {
+	UserAgent  string
+	ClientIP   string
+	DeviceName string
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// RevokedToken blacklists a single access token by its jti until it expires
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;index;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -8,16 +8,21 @@ import (
 )
 
 type User struct {
-	ID          uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	PhoneNumber string    `json:"phone_number" gorm:"uniqueIndex;not null" validate:"required"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	PhoneNumber  string    `json:"phone_number" gorm:"uniqueIndex;not null" validate:"required"`
+	Role         Role      `json:"role" gorm:"type:varchar(32);not null;default:'user'"`
+	TokenVersion int       `json:"-" gorm:"not null;default:0"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == uuid.Nil {
 		u.ID = uuid.New()
 	}
+	if u.Role == "" {
+		u.Role = RoleUser
+	}
 	return nil
 }
 
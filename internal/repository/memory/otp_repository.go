@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type otpRepository struct {
+	store *Store
+}
+
+func NewOTPRepository(store *Store) interfaces.OTPRepository {
+	return &otpRepository{store: store}
+}
+
+func (r *otpRepository) Create(ctx context.Context, otp *models.OTP) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if otp.ID == uuid.Nil {
+		otp.ID = uuid.New()
+	}
+	if _, ok := r.store.otps[otp.ID]; ok {
+		return fmt.Errorf("failed to create OTP: duplicate id %s", otp.ID)
+	}
+	if otp.CreatedAt.IsZero() {
+		otp.CreatedAt = time.Now()
+	}
+
+	r.store.otps[otp.ID] = *otp
+	return nil
+}
+
+func (r *otpRepository) GetPendingOTPs(ctx context.Context, phoneNumber string, limit int) ([]models.OTP, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	otps := []models.OTP{}
+	for _, otp := range r.store.otps {
+		if otp.PhoneNumber == phoneNumber && !otp.IsUsed {
+			otps = append(otps, otp)
+		}
+	}
+
+	sort.Slice(otps, func(i, j int) bool { return otps[i].CreatedAt.After(otps[j].CreatedAt) })
+	if len(otps) > limit {
+		otps = otps[:limit]
+	}
+
+	return otps, nil
+}
+
+// Consume marks a pending, unexpired OTP as used; the store lock makes it
+// atomic, so only one of several concurrent callers gets true
+func (r *otpRepository) Consume(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	otp, ok := r.store.otps[id]
+	if !ok || otp.IsUsed || !otp.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+
+	otp.IsUsed = true
+	r.store.otps[id] = otp
+	return true, nil
+}
+
+func (r *otpRepository) RecordFailedAttempt(ctx context.Context, phoneNumber string, maxFailures int) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	invalidated := false
+	for id, otp := range r.store.otps {
+		if otp.PhoneNumber != phoneNumber || otp.IsUsed || !otp.ExpiresAt.After(now) {
+			continue
+		}
+
+		otp.FailedAttempts++
+		otp.IsUsed = otp.FailedAttempts >= maxFailures
+		invalidated = invalidated || otp.IsUsed
+		r.store.otps[id] = otp
+	}
+
+	return invalidated, nil
+}
+
+func (r *otpRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for id, otp := range r.store.otps {
+		if otp.ExpiresAt.Before(before) {
+			delete(r.store.otps, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+type otpAttemptRepository struct {
+	store *Store
+}
+
+func NewOTPAttemptRepository(store *Store) interfaces.OTPAttemptRepository {
+	return &otpAttemptRepository{store: store}
+}
+
+func (r *otpAttemptRepository) Create(ctx context.Context, attempt *models.OTPAttempt) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if attempt.ID == uuid.Nil {
+		attempt.ID = uuid.New()
+	}
+	if _, ok := r.store.attempts[attempt.ID]; ok {
+		return fmt.Errorf("failed to create OTP attempt: duplicate id %s", attempt.ID)
+	}
+	if attempt.AttemptTime.IsZero() {
+		attempt.AttemptTime = time.Now()
+	}
+
+	r.store.attempts[attempt.ID] = *attempt
+	return nil
+}
+
+func (r *otpAttemptRepository) CountRecentAttempts(ctx context.Context, phoneNumber string, since time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var count int64
+	for _, attempt := range r.store.attempts {
+		if attempt.PhoneNumber == phoneNumber && attempt.AttemptTime.After(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *otpAttemptRepository) DeleteOldAttempts(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for id, attempt := range r.store.attempts {
+		if attempt.AttemptTime.Before(before) {
+			delete(r.store.attempts, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+type otpLockoutRepository struct {
+	store *Store
+}
+
+func NewOTPLockoutRepository(store *Store) interfaces.OTPLockoutRepository {
+	return &otpLockoutRepository{store: store}
+}
+
+func (r *otpLockoutRepository) Get(ctx context.Context, phoneNumber string) (*models.OTPLockout, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	lockout, ok := r.store.lockouts[phoneNumber]
+	if !ok {
+		return &models.OTPLockout{PhoneNumber: phoneNumber}, nil
+	}
+
+	return &lockout, nil
+}
+
+func (r *otpLockoutRepository) Save(ctx context.Context, lockout *models.OTPLockout) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	lockout.UpdatedAt = time.Now()
+	r.store.lockouts[lockout.PhoneNumber] = *lockout
+	return nil
+}
+
+func (r *otpLockoutRepository) Delete(ctx context.Context, phoneNumber string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.lockouts, phoneNumber)
+	return nil
+}
+
+// DeleteStale removes lockouts that ended before the given time
+func (r *otpLockoutRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for phoneNumber, lockout := range r.store.lockouts {
+		if lockout.LockedUntil.Before(before) {
+			delete(r.store.lockouts, phoneNumber)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
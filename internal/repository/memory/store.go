@@ -0,0 +1,93 @@
+// Package memory keeps users and OTPs in process memory. It backs tests and
+// tools that should not need a database; nothing survives a restart.
+package memory
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Store holds the records shared by the repositories built on it
+type Store struct {
+	mu       sync.Mutex
+	users    map[uuid.UUID]models.User
+	otps     map[uuid.UUID]models.OTP
+	attempts map[uuid.UUID]models.OTPAttempt
+	lockouts map[string]models.OTPLockout
+
+	// txMu serialises transactions
+	txMu sync.Mutex
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:    map[uuid.UUID]models.User{},
+		otps:     map[uuid.UUID]models.OTP{},
+		attempts: map[uuid.UUID]models.OTPAttempt{},
+		lockouts: map[string]models.OTPLockout{},
+	}
+}
+
+type snapshot struct {
+	users    map[uuid.UUID]models.User
+	otps     map[uuid.UUID]models.OTP
+	attempts map[uuid.UUID]models.OTPAttempt
+	lockouts map[string]models.OTPLockout
+}
+
+func (s *Store) snapshot() snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return snapshot{
+		users:    maps.Clone(s.users),
+		otps:     maps.Clone(s.otps),
+		attempts: maps.Clone(s.attempts),
+		lockouts: maps.Clone(s.lockouts),
+	}
+}
+
+func (s *Store) restore(snap snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users, s.otps, s.attempts, s.lockouts = snap.users, snap.otps, snap.attempts, snap.lockouts
+}
+
+type transactor struct {
+	store *Store
+}
+
+// NewTransactor runs transactions against store one at a time. A failed
+// transaction is rolled back by restoring the records as they were when it
+// began, which also discards writes made outside it in the meantime.
+func NewTransactor(store *Store) interfaces.Transactor {
+	return &transactor{store: store}
+}
+
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(repos interfaces.TxRepositories) error) error {
+	t.store.txMu.Lock()
+	defer t.store.txMu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	snap := t.store.snapshot()
+	err := fn(interfaces.TxRepositories{
+		OTPs:        NewOTPRepository(t.store),
+		OTPLockouts: NewOTPLockoutRepository(t.store),
+		Users:       NewUserRepository(t.store),
+	})
+	if err != nil {
+		t.store.restore(snap)
+	}
+
+	return err
+}
@@ -0,0 +1,20 @@
+package memory
+
+import (
+	"testing"
+
+	"go-auth/internal/repository/repositorytest"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		store := NewStore()
+		return repositorytest.Repositories{
+			Users:       NewUserRepository(store),
+			OTPs:        NewOTPRepository(store),
+			OTPAttempts: NewOTPAttemptRepository(store),
+			OTPLockouts: NewOTPLockoutRepository(store),
+			Transactor:  NewTransactor(store),
+		}
+	})
+}
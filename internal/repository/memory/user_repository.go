@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+	"go-auth/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) interfaces.UserRepository {
+	return &userRepository{store: store}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.create(user)
+}
+
+// create inserts user, filling in the defaults the database would. The
+// caller holds the store lock.
+func (r *userRepository) create(user *models.User) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+
+	if _, ok := r.store.users[user.ID]; ok {
+		return fmt.Errorf("failed to create user: duplicate id %s", user.ID)
+	}
+	if r.findByPhoneNumber(user.PhoneNumber) != nil {
+		return fmt.Errorf("failed to create user: phone number already registered")
+	}
+
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+func (r *userRepository) findByPhoneNumber(phoneNumber string) *models.User {
+	for _, user := range r.store.users {
+		if user.PhoneNumber == phoneNumber {
+			return &user
+		}
+	}
+	return nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil, utils.ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+func (r *userRepository) GetByPhoneNumber(ctx context.Context, phoneNumber string) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user := r.findByPhoneNumber(phoneNumber)
+	if user == nil {
+		return nil, utils.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) FindOrCreateByPhoneNumber(ctx context.Context, phoneNumber string) (*models.User, bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if existing := r.findByPhoneNumber(phoneNumber); existing != nil {
+		return existing, false, nil
+	}
+
+	user := &models.User{PhoneNumber: phoneNumber}
+	if err := r.create(user); err != nil {
+		return nil, false, err
+	}
+
+	return user, true, nil
+}
+
+func (r *userRepository) GetUsers(ctx context.Context, page, limit int, search string) ([]models.User, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var matched []models.User
+	for _, user := range r.store.users {
+		if strings.Contains(user.PhoneNumber, search) {
+			matched = append(matched, user)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool { return matched[i].CreatedAt.After(matched[j].CreatedAt) })
+
+	total := int64(len(matched))
+	offset := (page - 1) * limit
+	if offset >= len(matched) {
+		return []models.User{}, total, nil
+	}
+	end := min(offset+limit, len(matched))
+
+	return matched[offset:end], total, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if existing := r.findByPhoneNumber(user.PhoneNumber); existing != nil && existing.ID != user.ID {
+		return fmt.Errorf("failed to update user: phone number already registered")
+	}
+
+	user.UpdatedAt = time.Now()
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id uuid.UUID, role models.Role) error {
+	return r.modify(id, func(user *models.User) {
+		user.Role = role
+		user.UpdatedAt = time.Now()
+	})
+}
+
+func (r *userRepository) IncrementTokenVersion(ctx context.Context, id uuid.UUID) error {
+	return r.modify(id, func(user *models.User) {
+		user.TokenVersion++
+	})
+}
+
+func (r *userRepository) modify(id uuid.UUID, fn func(user *models.User)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return utils.ErrUserNotFound
+	}
+
+	fn(&user)
+	r.store.users[id] = user
+	return nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[id]; !ok {
+		return utils.ErrUserNotFound
+	}
+
+	delete(r.store.users, id)
+	return nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type otpRepository struct {
@@ -21,69 +23,92 @@ func NewOTPRepository(db *gorm.DB) interfaces.OTPRepository {
 	return &otpRepository{db: db}
 }
 
-func (r *otpRepository) Create(otp *models.OTP) error {
-	if err := r.db.Create(otp).Error; err != nil {
-		utils.LogDatabaseOperation("create", "otps", false, err.Error())
+func (r *otpRepository) Create(ctx context.Context, otp *models.OTP) error {
+	if err := r.db.WithContext(ctx).Create(otp).Error; err != nil {
+		utils.LogDatabaseOperation(ctx, "create", "otps", false, err.Error())
 		return fmt.Errorf("failed to create OTP: %w", err)
 	}
 
-	utils.LogDatabaseOperation("create", "otps", true, "")
+	utils.LogDatabaseOperation(ctx, "create", "otps", true, "")
 	return nil
 }
 
-func (r *otpRepository) GetValidOTP(phoneNumber, code string) (*models.OTP, error) {
-	var otp models.OTP
-	err := r.db.Where("phone_number = ? AND code = ? AND is_used = false", phoneNumber, code).
-		First(&otp).Error
+func (r *otpRepository) GetPendingOTPs(ctx context.Context, phoneNumber string, limit int) ([]models.OTP, error) {
+	var otps []models.OTP
+	err := r.db.WithContext(ctx).Where("phone_number = ? AND is_used = false", phoneNumber).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&otps).Error
 
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, utils.ErrInvalidOTP
-		}
-		utils.LogDatabaseOperation("find", "otps", false, err.Error())
-		return nil, fmt.Errorf("failed to get OTP: %w", err)
+		utils.LogDatabaseOperation(ctx, "find", "otps", false, err.Error())
+		return nil, fmt.Errorf("failed to get pending OTPs: %w", err)
 	}
 
-	if otp.IsExpired() {
-		return nil, utils.ErrOTPExpired
+	return otps, nil
+}
+
+// Consume marks a pending, unexpired OTP as used. The conditional update is
+// the single point of truth: of several concurrent callers only one gets true.
+func (r *otpRepository) Consume(ctx context.Context, id uuid.UUID) (bool, error) {
+	var consumed []models.OTP
+	result := r.db.WithContext(ctx).Model(&consumed).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("id = ? AND is_used = false AND expires_at > ?", id, time.Now()).
+		Update("is_used", true)
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "update", "otps", false, result.Error.Error())
+		return false, fmt.Errorf("failed to consume OTP: %w", result.Error)
 	}
 
-	return &otp, nil
+	utils.LogDatabaseOperation(ctx, "update", "otps", true, "")
+	return len(consumed) == 1, nil
 }
 
-func (r *otpRepository) MarkAsUsed(id uuid.UUID) error {
-	result := r.db.Model(&models.OTP{}).Where("id = ?", id).Update("is_used", true)
+func (r *otpRepository) RecordFailedAttempt(ctx context.Context, phoneNumber string, maxFailures int) (bool, error) {
+	var rows []struct {
+		IsUsed bool
+	}
 
-	if result.Error != nil {
-		utils.LogDatabaseOperation("update", "otps", false, result.Error.Error())
-		return fmt.Errorf("failed to mark OTP as used: %w", result.Error)
+	// Find rather than Scan so the statement runs under the query deadline
+	err := r.db.WithContext(ctx).Raw(`UPDATE otps
+		SET failed_attempts = failed_attempts + 1, is_used = (failed_attempts + 1 >= ?)
+		WHERE phone_number = ? AND is_used = false AND expires_at > ?
+		RETURNING is_used`, maxFailures, phoneNumber, time.Now()).
+		Find(&rows).Error
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "update", "otps", false, err.Error())
+		return false, fmt.Errorf("failed to record failed OTP attempt: %w", err)
 	}
 
-	if result.RowsAffected == 0 {
-		return utils.ErrInvalidOTP
+	for _, row := range rows {
+		if row.IsUsed {
+			return true, nil
+		}
 	}
 
-	utils.LogDatabaseOperation("update", "otps", true, "")
-	return nil
+	return false, nil
 }
 
-func (r *otpRepository) DeleteExpired() error {
-	result := r.db.Where("expires_at < ?", time.Now()).Delete(&models.OTP{})
+func (r *otpRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.OTP{})
 
 	if result.Error != nil {
-		utils.LogDatabaseOperation("cleanup", "otps", false, result.Error.Error())
-		return fmt.Errorf("failed to cleanup expired OTPs: %w", result.Error)
+		utils.LogDatabaseOperation(ctx, "cleanup", "otps", false, result.Error.Error())
+		return 0, fmt.Errorf("failed to cleanup expired OTPs: %w", result.Error)
 	}
 
 	if result.RowsAffected > 0 {
-		utils.LogWithFields(map[string]interface{}{
+		utils.LogWithContext(ctx).WithFields(map[string]interface{}{
 			"rows_affected": result.RowsAffected,
 			"type":          "cleanup",
 			"table":         "otps",
 		}).Info("Cleaned up expired OTPs")
 	}
 
-	return nil
+	return result.RowsAffected, nil
 }
 
 type otpAttemptRepository struct {
@@ -94,36 +119,95 @@ func NewOTPAttemptRepository(db *gorm.DB) interfaces.OTPAttemptRepository {
 	return &otpAttemptRepository{db: db}
 }
 
-func (r *otpAttemptRepository) Create(attempt *models.OTPAttempt) error {
-	if err := r.db.Create(attempt).Error; err != nil {
-		utils.LogDatabaseOperation("create", "otp_attempts", false, err.Error())
+func (r *otpAttemptRepository) Create(ctx context.Context, attempt *models.OTPAttempt) error {
+	if err := r.db.WithContext(ctx).Create(attempt).Error; err != nil {
+		utils.LogDatabaseOperation(ctx, "create", "otp_attempts", false, err.Error())
 		return fmt.Errorf("failed to create OTP attempt: %w", err)
 	}
 
 	return nil
 }
 
-func (r *otpAttemptRepository) CountRecentAttempts(phoneNumber string, since time.Time) (int64, error) {
+func (r *otpAttemptRepository) CountRecentAttempts(ctx context.Context, phoneNumber string, since time.Time) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.OTPAttempt{}).
+	err := r.db.WithContext(ctx).Model(&models.OTPAttempt{}).
 		Where("phone_number = ? AND attempt_time > ?", phoneNumber, since).
 		Count(&count).Error
 
 	if err != nil {
-		utils.LogDatabaseOperation("count", "otp_attempts", false, err.Error())
+		utils.LogDatabaseOperation(ctx, "count", "otp_attempts", false, err.Error())
 		return 0, fmt.Errorf("failed to count OTP attempts: %w", err)
 	}
 
 	return count, nil
 }
 
-func (r *otpAttemptRepository) DeleteOldAttempts(before time.Time) error {
-	result := r.db.Where("attempt_time < ?", before).Delete(&models.OTPAttempt{})
+func (r *otpAttemptRepository) DeleteOldAttempts(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("attempt_time < ?", before).Delete(&models.OTPAttempt{})
 
 	if result.Error != nil {
-		utils.LogDatabaseOperation("cleanup", "otp_attempts", false, result.Error.Error())
-		return fmt.Errorf("failed to cleanup old OTP attempts: %w", result.Error)
+		utils.LogDatabaseOperation(ctx, "cleanup", "otp_attempts", false, result.Error.Error())
+		return 0, fmt.Errorf("failed to cleanup old OTP attempts: %w", result.Error)
 	}
 
+	return result.RowsAffected, nil
+}
+
+type otpLockoutRepository struct {
+	db *gorm.DB
+}
+
+func NewOTPLockoutRepository(db *gorm.DB) interfaces.OTPLockoutRepository {
+	return &otpLockoutRepository{db: db}
+}
+
+func (r *otpLockoutRepository) Get(ctx context.Context, phoneNumber string) (*models.OTPLockout, error) {
+	var lockout models.OTPLockout
+	err := r.db.WithContext(ctx).Where("phone_number = ?", phoneNumber).First(&lockout).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.OTPLockout{PhoneNumber: phoneNumber}, nil
+		}
+		utils.LogDatabaseOperation(ctx, "find", "otp_lockouts", false, err.Error())
+		return nil, fmt.Errorf("failed to get OTP lockout: %w", err)
+	}
+
+	return &lockout, nil
+}
+
+func (r *otpLockoutRepository) Save(ctx context.Context, lockout *models.OTPLockout) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "phone_number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"level", "locked_until", "updated_at"}),
+	}).Create(lockout).Error
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "upsert", "otp_lockouts", false, err.Error())
+		return fmt.Errorf("failed to save OTP lockout: %w", err)
+	}
+
+	utils.LogDatabaseOperation(ctx, "upsert", "otp_lockouts", true, "")
 	return nil
 }
+
+func (r *otpLockoutRepository) Delete(ctx context.Context, phoneNumber string) error {
+	if err := r.db.WithContext(ctx).Where("phone_number = ?", phoneNumber).Delete(&models.OTPLockout{}).Error; err != nil {
+		utils.LogDatabaseOperation(ctx, "delete", "otp_lockouts", false, err.Error())
+		return fmt.Errorf("failed to delete OTP lockout: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStale removes lockouts that ended before the given time
+func (r *otpLockoutRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("locked_until < ?", before).Delete(&models.OTPLockout{})
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "cleanup", "otp_lockouts", false, result.Error.Error())
+		return 0, fmt.Errorf("failed to cleanup OTP lockouts: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type rateLimitRepository struct {
+	db *gorm.DB
+}
+
+// NewRateLimitRepository keeps rate-limiter state in the rate_limits table.
+// Each update locks its row, so concurrent requests for one key queue up
+// rather than overcount.
+func NewRateLimitRepository(db *gorm.DB) interfaces.RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
+
+func (r *rateLimitRepository) Update(ctx context.Context, key string, ttl time.Duration, fn func(ratelimit.State) ratelimit.State) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Make sure there is a row to lock; an expired one reads as absent
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.RateLimit{Key: key}).Error; err != nil {
+			return err
+		}
+
+		var row models.RateLimit
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ?", key).First(&row).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var state ratelimit.State
+		if row.ExpiresAt.After(now) {
+			state = ratelimit.State{Count: row.Count, Previous: row.Previous, Time: row.Time}
+		}
+
+		state = fn(state)
+		return tx.Model(&models.RateLimit{}).Where("key = ?", key).Updates(map[string]interface{}{
+			"count":      state.Count,
+			"previous":   state.Previous,
+			"time":       state.Time,
+			"expires_at": now.Add(ttl),
+		}).Error
+	})
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "upsert", "rate_limits", false, err.Error())
+		return fmt.Errorf("failed to update rate limit: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes keys that expired before the given time
+func (r *rateLimitRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.RateLimit{})
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "cleanup", "rate_limits", false, result.Error.Error())
+		return 0, fmt.Errorf("failed to cleanup rate limits: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/ratelimit/ratelimittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitRepositorySQLite(t *testing.T) {
+	ratelimittest.Run(t, func(t *testing.T) ratelimit.Store {
+		return NewRateLimitRepository(openSQLite(t))
+	})
+}
+
+// TestRateLimitRepositoryPostgres needs a disposable database, see
+// TestConformancePostgres
+func TestRateLimitRepositoryPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" || testing.Short() {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+
+	db := openPostgres(t, dsn)
+	ratelimittest.Run(t, func(t *testing.T) ratelimit.Store {
+		return NewRateLimitRepository(db)
+	})
+}
+
+func TestRateLimitRepositoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRateLimitRepository(openSQLite(t))
+
+	require.NoError(t, repo.Update(ctx, "expiring", 10*time.Millisecond, func(ratelimit.State) ratelimit.State {
+		return ratelimit.State{Count: 1, Time: time.Now()}
+	}))
+	require.NoError(t, repo.Update(ctx, "live", time.Hour, func(ratelimit.State) ratelimit.State {
+		return ratelimit.State{Count: 1, Time: time.Now()}
+	}))
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, repo.Update(ctx, "expiring", 10*time.Millisecond, func(state ratelimit.State) ratelimit.State {
+		assert.Equal(t, ratelimit.State{}, state, "an expired key starts over")
+		return state
+	}))
+	time.Sleep(20 * time.Millisecond)
+
+	deleted, err := repo.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,32 @@
+// Package redis keeps OTPs and OTP request attempts in Redis. Keys expire on
+// their own, so nothing has to be cleaned up, and every read-modify-write runs
+// as a Lua script, so it is atomic across replicas.
+//
+// The scripts touch keys they derive from others, which Redis Cluster does
+// not allow; use a single server or a primary with replicas.
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"go-auth/internal/config"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Connect opens a client for cfg.URL and checks that the server answers
+func Connect(ctx context.Context, cfg *config.RedisConfig) (*goredis.Client, error) {
+	options, err := goredis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	client := goredis.NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return client, nil
+}
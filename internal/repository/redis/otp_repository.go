@@ -0,0 +1,314 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+	"go-auth/pkg/utils"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// expiredOTPRetention keeps an OTP around briefly after it expires, so a late
+// verification is told the code expired rather than that it is wrong
+const expiredOTPRetention = 10 * time.Minute
+
+// An OTP is a hash at <prefix>otp:<id>. The sorted set <prefix>otp:phone:<phone>
+// indexes a phone number's OTPs by creation time; members whose hash has
+// expired are dropped when they are next read.
+
+// createOTPScript stores the hash and indexes it. The index lives as long as
+// its longest-lived OTP.
+//
+// KEYS: otp hash, phone index
+// ARGV: id, created_at score, ttl ms, hash field/value pairs...
+var createOTPScript = goredis.NewScript(`
+redis.call('HSET', KEYS[1], unpack(ARGV, 4))
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+if redis.call('PTTL', KEYS[2]) < tonumber(ARGV[3]) then
+	redis.call('PEXPIRE', KEYS[2], ARGV[3])
+end
+return 1
+`)
+
+// consumeOTPScript marks a pending, unexpired OTP as used. Of several
+// concurrent callers only one gets 1.
+//
+// KEYS: otp hash
+// ARGV: now ms
+var consumeOTPScript = goredis.NewScript(`
+local otp = redis.call('HMGET', KEYS[1], 'is_used', 'expires_at')
+if otp[1] ~= '0' or tonumber(otp[2]) <= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'is_used', '1')
+return 1
+`)
+
+// recordFailedAttemptScript counts a wrong guess against every pending,
+// unexpired OTP of a phone number and invalidates those that reach the limit.
+// Returns 1 if any was invalidated.
+//
+// KEYS: phone index
+// ARGV: now ms, max failures, otp key prefix
+var recordFailedAttemptScript = goredis.NewScript(`
+local invalidated = 0
+for _, id in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+	local key = ARGV[3] .. id
+	local otp = redis.call('HMGET', key, 'is_used', 'expires_at')
+	if otp[1] == '0' and tonumber(otp[2]) > tonumber(ARGV[1]) then
+		local failures = redis.call('HINCRBY', key, 'failed_attempts', 1)
+		if failures >= tonumber(ARGV[2]) then
+			redis.call('HSET', key, 'is_used', '1')
+			invalidated = 1
+		end
+	end
+end
+return invalidated
+`)
+
+type otpRepository struct {
+	client goredis.UniversalClient
+	prefix string
+}
+
+// NewOTPRepository stores OTPs under keys starting with prefix. Each OTP
+// expires shortly after its ExpiresAt.
+func NewOTPRepository(client goredis.UniversalClient, prefix string) interfaces.OTPRepository {
+	return &otpRepository{client: client, prefix: prefix}
+}
+
+func (r *otpRepository) otpKey(id string) string {
+	return r.prefix + "otp:" + id
+}
+
+func (r *otpRepository) phoneKey(phoneNumber string) string {
+	return r.prefix + "otp:phone:" + phoneNumber
+}
+
+func (r *otpRepository) Create(ctx context.Context, otp *models.OTP) error {
+	if otp.ID == uuid.Nil {
+		otp.ID = uuid.New()
+	}
+	if otp.CreatedAt.IsZero() {
+		otp.CreatedAt = time.Now()
+	}
+
+	// Already past its retention: there is nothing to store
+	ttl := time.Until(otp.ExpiresAt.Add(expiredOTPRetention))
+	if ttl <= 0 {
+		return nil
+	}
+
+	id := otp.ID.String()
+	err := createOTPScript.Run(ctx, r.client,
+		[]string{r.otpKey(id), r.phoneKey(otp.PhoneNumber)},
+		id, otp.CreatedAt.UnixMicro(), ttl.Milliseconds(),
+		"phone_number", otp.PhoneNumber,
+		"code_hash", otp.CodeHash,
+		"created_at", otp.CreatedAt.UnixMicro(),
+		"expires_at", otp.ExpiresAt.UnixMilli(),
+		"is_used", formatBool(otp.IsUsed),
+		"failed_attempts", otp.FailedAttempts,
+	).Err()
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "create", "redis:otps", false, err.Error())
+		return fmt.Errorf("failed to create OTP: %w", err)
+	}
+
+	utils.LogDatabaseOperation(ctx, "create", "redis:otps", true, "")
+	return nil
+}
+
+func (r *otpRepository) GetPendingOTPs(ctx context.Context, phoneNumber string, limit int) ([]models.OTP, error) {
+	phoneKey := r.phoneKey(phoneNumber)
+
+	ids, err := r.client.ZRevRange(ctx, phoneKey, 0, -1).Result()
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "find", "redis:otps", false, err.Error())
+		return nil, fmt.Errorf("failed to get pending OTPs: %w", err)
+	}
+
+	cmds := make([]*goredis.MapStringStringCmd, len(ids))
+	_, err = r.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, r.otpKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "find", "redis:otps", false, err.Error())
+		return nil, fmt.Errorf("failed to get pending OTPs: %w", err)
+	}
+
+	otps := []models.OTP{}
+	var gone []interface{}
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			gone = append(gone, ids[i])
+			continue
+		}
+
+		otp, err := decodeOTP(ids[i], fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode OTP %s: %w", ids[i], err)
+		}
+		if otp.IsUsed || len(otps) == limit {
+			continue
+		}
+		otps = append(otps, *otp)
+	}
+
+	// Best effort: a failure only leaves stale members for the next read
+	if len(gone) > 0 {
+		r.client.ZRem(ctx, phoneKey, gone...)
+	}
+
+	return otps, nil
+}
+
+func (r *otpRepository) Consume(ctx context.Context, id uuid.UUID) (bool, error) {
+	consumed, err := consumeOTPScript.Run(ctx, r.client,
+		[]string{r.otpKey(id.String())}, time.Now().UnixMilli()).Int()
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "update", "redis:otps", false, err.Error())
+		return false, fmt.Errorf("failed to consume OTP: %w", err)
+	}
+
+	utils.LogDatabaseOperation(ctx, "update", "redis:otps", true, "")
+	return consumed == 1, nil
+}
+
+func (r *otpRepository) RecordFailedAttempt(ctx context.Context, phoneNumber string, maxFailures int) (bool, error) {
+	invalidated, err := recordFailedAttemptScript.Run(ctx, r.client,
+		[]string{r.phoneKey(phoneNumber)}, time.Now().UnixMilli(), maxFailures, r.otpKey("")).Int()
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "update", "redis:otps", false, err.Error())
+		return false, fmt.Errorf("failed to record failed OTP attempt: %w", err)
+	}
+
+	return invalidated == 1, nil
+}
+
+// DeleteExpired has nothing to do: expired OTPs are removed by Redis
+func (r *otpRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func decodeOTP(id string, fields map[string]string) (*models.OTP, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := strconv.ParseInt(fields["created_at"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := strconv.ParseInt(fields["expires_at"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	failedAttempts, err := strconv.Atoi(fields["failed_attempts"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OTP{
+		ID:             parsedID,
+		PhoneNumber:    fields["phone_number"],
+		CodeHash:       fields["code_hash"],
+		CreatedAt:      time.UnixMicro(createdAt),
+		ExpiresAt:      time.UnixMilli(expiresAt),
+		IsUsed:         fields["is_used"] == "1",
+		FailedAttempts: failedAttempts,
+	}, nil
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// Attempts are a sorted set per phone number at <prefix>otp_attempts:<phone>,
+// scored by attempt time in microseconds. Entries older than the rate window
+// are trimmed on every insert and the key expires with the newest entry.
+
+// addAttemptScript records an attempt and trims the window in one step
+//
+// KEYS: attempts set
+// ARGV: attempt id, attempt time µs, window start µs, window ms
+var addAttemptScript = goredis.NewScript(`
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', '(' .. ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return redis.call('ZCARD', KEYS[1])
+`)
+
+type otpAttemptRepository struct {
+	client goredis.UniversalClient
+	prefix string
+	window time.Duration
+}
+
+// NewOTPAttemptRepository keeps attempts for window, which must cover the
+// longest period CountRecentAttempts is asked about: the OTP rate window
+func NewOTPAttemptRepository(client goredis.UniversalClient, prefix string, window time.Duration) interfaces.OTPAttemptRepository {
+	return &otpAttemptRepository{client: client, prefix: prefix, window: window}
+}
+
+func (r *otpAttemptRepository) key(phoneNumber string) string {
+	return r.prefix + "otp_attempts:" + phoneNumber
+}
+
+func (r *otpAttemptRepository) Create(ctx context.Context, attempt *models.OTPAttempt) error {
+	if attempt.ID == uuid.Nil {
+		attempt.ID = uuid.New()
+	}
+	if attempt.AttemptTime.IsZero() {
+		attempt.AttemptTime = time.Now()
+	}
+
+	err := addAttemptScript.Run(ctx, r.client, []string{r.key(attempt.PhoneNumber)},
+		attempt.ID.String(),
+		attempt.AttemptTime.UnixMicro(),
+		time.Now().Add(-r.window).UnixMicro(),
+		r.window.Milliseconds(),
+	).Err()
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "create", "redis:otp_attempts", false, err.Error())
+		return fmt.Errorf("failed to create OTP attempt: %w", err)
+	}
+
+	return nil
+}
+
+func (r *otpAttemptRepository) CountRecentAttempts(ctx context.Context, phoneNumber string, since time.Time) (int64, error) {
+	count, err := r.client.ZCount(ctx, r.key(phoneNumber), "("+strconv.FormatInt(since.UnixMicro(), 10), "+inf").Result()
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "count", "redis:otp_attempts", false, err.Error())
+		return 0, fmt.Errorf("failed to count OTP attempts: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteOldAttempts has nothing to do: attempts age out of their window and
+// the keys expire
+func (r *otpAttemptRepository) DeleteOldAttempts(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-auth/internal/interfaces"
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/utils"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// maxRateLimitRetries bounds how often an update is retried when another
+// request changed the same key in between
+const maxRateLimitRetries = 50
+
+type rateLimitRepository struct {
+	client goredis.UniversalClient
+	prefix string
+}
+
+// NewRateLimitRepository keeps each rate-limit key in a hash at
+// <prefix>ratelimit:<key> that expires on its own. Updates are optimistic
+// transactions: a write that raced another one is retried.
+func NewRateLimitRepository(client goredis.UniversalClient, prefix string) interfaces.RateLimitRepository {
+	return &rateLimitRepository{client: client, prefix: prefix}
+}
+
+func (r *rateLimitRepository) Update(ctx context.Context, key string, ttl time.Duration, fn func(ratelimit.State) ratelimit.State) error {
+	key = r.prefix + "ratelimit:" + key
+
+	update := func(tx *goredis.Tx) error {
+		fields, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+
+		state, err := decodeRateLimit(fields)
+		if err != nil {
+			return err
+		}
+		state = fn(state)
+
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.HSet(ctx, key,
+				"count", state.Count,
+				"previous", state.Previous,
+				"time", state.Time.UnixMicro(),
+			)
+			pipe.PExpire(ctx, key, ttl)
+			return nil
+		})
+		return err
+	}
+
+	var err error
+	for i := 0; i < maxRateLimitRetries; i++ {
+		err = r.client.Watch(ctx, update, key)
+		if !errors.Is(err, goredis.TxFailedErr) {
+			break
+		}
+	}
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "upsert", "redis:rate_limits", false, err.Error())
+		return fmt.Errorf("failed to update rate limit: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired has nothing to do: keys expire in Redis
+func (r *rateLimitRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func decodeRateLimit(fields map[string]string) (ratelimit.State, error) {
+	if len(fields) == 0 {
+		return ratelimit.State{}, nil
+	}
+
+	count, err := strconv.ParseFloat(fields["count"], 64)
+	if err != nil {
+		return ratelimit.State{}, err
+	}
+	previous, err := strconv.ParseFloat(fields["previous"], 64)
+	if err != nil {
+		return ratelimit.State{}, err
+	}
+	at, err := strconv.ParseInt(fields["time"], 10, 64)
+	if err != nil {
+		return ratelimit.State{}, err
+	}
+
+	return ratelimit.State{Count: count, Previous: previous, Time: time.UnixMicro(at)}, nil
+}
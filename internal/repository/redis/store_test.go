@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-auth/internal/models"
+	"go-auth/internal/repository/repositorytest"
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/ratelimit/ratelimittest"
+	"go-auth/pkg/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPrefix = "test:"
+
+func TestMain(m *testing.M) {
+	utils.InitLogger()
+	m.Run()
+}
+
+func newClient(t *testing.T) (*miniredis.Miniredis, *goredis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		_, client := newClient(t)
+		return repositorytest.Repositories{
+			OTPs:         NewOTPRepository(client, testPrefix),
+			OTPAttempts:  NewOTPAttemptRepository(client, testPrefix, time.Hour),
+			NativeExpiry: true,
+		}
+	})
+}
+
+func TestOTPsExpire(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newClient(t)
+	repo := NewOTPRepository(client, testPrefix)
+
+	otp := &models.OTP{PhoneNumber: "+15550000001", CodeHash: "hash", ExpiresAt: time.Now().Add(time.Minute)}
+	require.NoError(t, repo.Create(ctx, otp))
+
+	ttl := mr.TTL(testPrefix + "otp:" + otp.ID.String())
+	assert.InDelta(t, (time.Minute + expiredOTPRetention).Seconds(), ttl.Seconds(), 1)
+
+	mr.FastForward(time.Minute + expiredOTPRetention)
+
+	otps, err := repo.GetPendingOTPs(ctx, otp.PhoneNumber, 5)
+	require.NoError(t, err)
+	assert.Empty(t, otps)
+	assert.False(t, mr.Exists(testPrefix+"otp:phone:"+otp.PhoneNumber), "the index expires with its OTPs")
+}
+
+func TestAttemptsSlideOutOfTheWindow(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newClient(t)
+	repo := NewOTPAttemptRepository(client, testPrefix, time.Hour)
+	phoneNumber := "+15550000001"
+	now := time.Now()
+
+	for _, at := range []time.Time{now.Add(-2 * time.Hour), now.Add(-30 * time.Minute), now} {
+		require.NoError(t, repo.Create(ctx, &models.OTPAttempt{PhoneNumber: phoneNumber, AttemptTime: at}))
+	}
+
+	members, err := client.ZCard(ctx, testPrefix+"otp_attempts:"+phoneNumber).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), members, "attempts outside the window are trimmed on insert")
+
+	count, err := repo.CountRecentAttempts(ctx, phoneNumber, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	mr.FastForward(time.Hour)
+	assert.False(t, mr.Exists(testPrefix+"otp_attempts:"+phoneNumber), "the set expires an hour after the last attempt")
+}
+
+func TestRateLimitRepository(t *testing.T) {
+	ratelimittest.Run(t, func(t *testing.T) ratelimit.Store {
+		_, client := newClient(t)
+		return NewRateLimitRepository(client, testPrefix)
+	})
+}
+
+func TestRateLimitsExpire(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newClient(t)
+	repo := NewRateLimitRepository(client, testPrefix)
+
+	require.NoError(t, repo.Update(ctx, "key", time.Minute, func(ratelimit.State) ratelimit.State {
+		return ratelimit.State{Count: 1, Time: time.Now()}
+	}))
+	assert.Equal(t, time.Minute, mr.TTL(testPrefix+"ratelimit:key"))
+
+	mr.FastForward(time.Minute)
+	assert.False(t, mr.Exists(testPrefix+"ratelimit:key"))
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go-auth/internal/database"
+	"go-auth/internal/repository/repositorytest"
+	"go-auth/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogger()
+	utils.Logger.SetLevel(logrus.PanicLevel)
+	os.Exit(m.Run())
+}
+
+func TestConformanceSQLite(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		return gormRepositories(openSQLite(t))
+	})
+}
+
+// TestConformancePostgres needs a disposable database, e.g.
+//
+// TEST_DATABASE_DSN="host=localhost user=postgres password=password dbname=go_auth_test sslmode=disable"
+func TestConformancePostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" || testing.Short() {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+
+	db := openPostgres(t, dsn)
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		return gormRepositories(db)
+	})
+}
+
+// openSQLite returns a migrated in-memory database that is closed with the
+// test
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.OpenSQLite(":memory:", &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	migrate(t, db, database.DriverSQLite)
+	return db
+}
+
+func openPostgres(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	migrate(t, db, database.DriverPostgres)
+	return db
+}
+
+func migrate(t *testing.T, db *gorm.DB, driver string) {
+	t.Helper()
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	migrations, err := database.EmbeddedMigrations(driver)
+	require.NoError(t, err)
+	migrator, err := database.NewMigrator(sqlDB, driver, migrations)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+}
+
+func gormRepositories(db *gorm.DB) repositorytest.Repositories {
+	return repositorytest.Repositories{
+		Users:       NewUserRepository(db),
+		OTPs:        NewOTPRepository(db),
+		OTPAttempts: NewOTPAttemptRepository(db),
+		OTPLockouts: NewOTPLockoutRepository(db),
+		Transactor:  NewTransactor(db, 0),
+	}
+}
@@ -0,0 +1,443 @@
+// Package repositorytest is a conformance suite for the repository
+// interfaces. Every backend runs it, so services behave the same on
+// Postgres, SQLite and in memory.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+	"go-auth/pkg/utils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Repositories is one backend under test. Backends that implement only some
+// of the interfaces leave the others nil and the tests using them are skipped.
+type Repositories struct {
+	Users       interfaces.UserRepository
+	OTPs        interfaces.OTPRepository
+	OTPAttempts interfaces.OTPAttemptRepository
+	OTPLockouts interfaces.OTPLockoutRepository
+	Transactor  interfaces.Transactor
+
+	// NativeExpiry is set for stores that expire rows on their own, e.g. with
+	// Redis TTLs. Their cleanup methods may delete nothing, and rows past
+	// their retention may never be visible at all.
+	NativeExpiry bool
+}
+
+// Run runs the suite against the backend returned by newRepositories, which
+// is called once per test. Backends may keep data between calls, e.g. a
+// shared Postgres database: every test uses phone numbers of its own and
+// leaves other rows alone.
+func Run(t *testing.T, newRepositories func(t *testing.T) Repositories) {
+	users := func(r Repositories) bool { return r.Users != nil }
+	otps := func(r Repositories) bool { return r.OTPs != nil }
+	attempts := func(r Repositories) bool { return r.OTPAttempts != nil }
+	lockouts := func(r Repositories) bool { return r.OTPLockouts != nil }
+	transactions := func(r Repositories) bool { return r.Users != nil && r.OTPs != nil && r.Transactor != nil }
+
+	tests := []struct {
+		name     string
+		supports func(r Repositories) bool
+		fn       func(t *testing.T, repos Repositories)
+	}{
+		{"UserCreateAndGet", users, testUserCreateAndGet},
+		{"UserNotFound", users, testUserNotFound},
+		{"UserDuplicatePhoneNumber", users, testUserDuplicatePhoneNumber},
+		{"UserFindOrCreate", users, testUserFindOrCreate},
+		{"UserList", users, testUserList},
+		{"UserUpdate", users, testUserUpdate},
+		{"UserDelete", users, testUserDelete},
+		{"OTPPending", otps, testOTPPending},
+		{"OTPConsume", otps, testOTPConsume},
+		{"OTPFailedAttempts", otps, testOTPFailedAttempts},
+		{"OTPDeleteExpired", otps, testOTPDeleteExpired},
+		{"OTPAttempts", attempts, testOTPAttempts},
+		{"OTPLockouts", lockouts, testOTPLockouts},
+		{"TransactionCommit", transactions, testTransactionCommit},
+		{"TransactionRollback", transactions, testTransactionRollback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := newRepositories(t)
+			if !tt.supports(repos) {
+				t.Skip("not implemented by this backend")
+			}
+			tt.fn(t, repos)
+		})
+	}
+}
+
+// phonePrefix returns a random number prefix, so tests sharing a database
+// do not see each other's rows
+func phonePrefix() string {
+	return fmt.Sprintf("+1999%06d", rand.IntN(1000000))
+}
+
+func testUserCreateAndGet(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := &models.User{PhoneNumber: phonePrefix() + "01"}
+
+	require.NoError(t, repos.Users.Create(ctx, user))
+	assert.NotEqual(t, uuid.Nil, user.ID)
+	assert.Equal(t, models.RoleUser, user.Role)
+	assert.False(t, user.CreatedAt.IsZero())
+
+	byID, err := repos.Users.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.PhoneNumber, byID.PhoneNumber)
+	assert.Equal(t, models.RoleUser, byID.Role)
+	assert.Equal(t, 0, byID.TokenVersion)
+
+	byPhone, err := repos.Users.GetByPhoneNumber(ctx, user.PhoneNumber)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, byPhone.ID)
+}
+
+func testUserNotFound(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	missing := uuid.New()
+
+	_, err := repos.Users.GetByID(ctx, missing)
+	assert.ErrorIs(t, err, utils.ErrUserNotFound)
+
+	_, err = repos.Users.GetByPhoneNumber(ctx, phonePrefix()+"01")
+	assert.ErrorIs(t, err, utils.ErrUserNotFound)
+
+	assert.ErrorIs(t, repos.Users.UpdateRole(ctx, missing, models.RoleAdmin), utils.ErrUserNotFound)
+	assert.ErrorIs(t, repos.Users.IncrementTokenVersion(ctx, missing), utils.ErrUserNotFound)
+	assert.ErrorIs(t, repos.Users.Delete(ctx, missing), utils.ErrUserNotFound)
+}
+
+func testUserDuplicatePhoneNumber(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+
+	require.NoError(t, repos.Users.Create(ctx, &models.User{PhoneNumber: phoneNumber}))
+	assert.Error(t, repos.Users.Create(ctx, &models.User{PhoneNumber: phoneNumber}))
+}
+
+func testUserFindOrCreate(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+
+	user, created, err := repos.Users.FindOrCreateByPhoneNumber(ctx, phoneNumber)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, phoneNumber, user.PhoneNumber)
+	assert.Equal(t, models.RoleUser, user.Role)
+
+	again, created, err := repos.Users.FindOrCreateByPhoneNumber(ctx, phoneNumber)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, user.ID, again.ID)
+}
+
+func testUserList(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	prefix := phonePrefix()
+	start := time.Now().Add(-time.Hour)
+
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		user := &models.User{
+			PhoneNumber: fmt.Sprintf("%s%02d", prefix, i),
+			CreatedAt:   start.Add(time.Duration(i) * time.Minute),
+		}
+		require.NoError(t, repos.Users.Create(ctx, user))
+		ids = append(ids, user.ID)
+	}
+
+	// Newest first
+	users, total, err := repos.Users.GetUsers(ctx, 1, 2, prefix)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, users, 2)
+	assert.Equal(t, ids[2], users[0].ID)
+	assert.Equal(t, ids[1], users[1].ID)
+
+	users, total, err = repos.Users.GetUsers(ctx, 2, 2, prefix)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, users, 1)
+	assert.Equal(t, ids[0], users[0].ID)
+
+	users, total, err = repos.Users.GetUsers(ctx, 1, 10, prefix+"01")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, users, 1)
+	assert.Equal(t, ids[1], users[0].ID)
+}
+
+func testUserUpdate(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := &models.User{PhoneNumber: phonePrefix() + "01"}
+	require.NoError(t, repos.Users.Create(ctx, user))
+
+	user.PhoneNumber = phonePrefix() + "02"
+	require.NoError(t, repos.Users.Update(ctx, user))
+
+	require.NoError(t, repos.Users.UpdateRole(ctx, user.ID, models.RoleSupport))
+	require.NoError(t, repos.Users.IncrementTokenVersion(ctx, user.ID))
+	require.NoError(t, repos.Users.IncrementTokenVersion(ctx, user.ID))
+
+	stored, err := repos.Users.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.PhoneNumber, stored.PhoneNumber)
+	assert.Equal(t, models.RoleSupport, stored.Role)
+	assert.Equal(t, 2, stored.TokenVersion)
+}
+
+func testUserDelete(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := &models.User{PhoneNumber: phonePrefix() + "01"}
+	require.NoError(t, repos.Users.Create(ctx, user))
+
+	require.NoError(t, repos.Users.Delete(ctx, user.ID))
+
+	_, err := repos.Users.GetByID(ctx, user.ID)
+	assert.ErrorIs(t, err, utils.ErrUserNotFound)
+}
+
+// createOTP stores an OTP for phoneNumber that expires after ttl, which may be
+// negative
+func createOTP(t *testing.T, repos Repositories, phoneNumber string, createdAt time.Time, ttl time.Duration) *models.OTP {
+	t.Helper()
+
+	otp := &models.OTP{
+		PhoneNumber: phoneNumber,
+		CodeHash:    "hash",
+		CreatedAt:   createdAt,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	require.NoError(t, repos.OTPs.Create(context.Background(), otp))
+	require.NotEqual(t, uuid.Nil, otp.ID)
+	return otp
+}
+
+func testOTPPending(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+	start := time.Now().Add(-time.Minute)
+
+	oldest := createOTP(t, repos, phoneNumber, start, time.Minute)
+	middle := createOTP(t, repos, phoneNumber, start.Add(time.Second), time.Minute)
+	newest := createOTP(t, repos, phoneNumber, start.Add(2*time.Second), time.Minute)
+	createOTP(t, repos, phonePrefix()+"02", start, time.Minute)
+
+	otps, err := repos.OTPs.GetPendingOTPs(ctx, phoneNumber, 2)
+	require.NoError(t, err)
+	require.Len(t, otps, 2)
+	assert.Equal(t, newest.ID, otps[0].ID)
+	assert.Equal(t, middle.ID, otps[1].ID)
+	assert.Equal(t, "hash", otps[0].CodeHash)
+
+	consumed, err := repos.OTPs.Consume(ctx, newest.ID)
+	require.NoError(t, err)
+	require.True(t, consumed)
+
+	otps, err = repos.OTPs.GetPendingOTPs(ctx, phoneNumber, 5)
+	require.NoError(t, err)
+	require.Len(t, otps, 2)
+	assert.Equal(t, middle.ID, otps[0].ID)
+	assert.Equal(t, oldest.ID, otps[1].ID)
+}
+
+func testOTPConsume(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+
+	otp := createOTP(t, repos, phoneNumber, time.Now(), time.Minute)
+
+	consumed, err := repos.OTPs.Consume(ctx, otp.ID)
+	require.NoError(t, err)
+	assert.True(t, consumed)
+
+	consumed, err = repos.OTPs.Consume(ctx, otp.ID)
+	require.NoError(t, err)
+	assert.False(t, consumed, "an OTP is consumed only once")
+
+	expired := createOTP(t, repos, phoneNumber, time.Now().Add(-time.Hour), -time.Minute)
+	consumed, err = repos.OTPs.Consume(ctx, expired.ID)
+	require.NoError(t, err)
+	assert.False(t, consumed, "an expired OTP cannot be consumed")
+
+	consumed, err = repos.OTPs.Consume(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.False(t, consumed)
+}
+
+func testOTPFailedAttempts(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+
+	createOTP(t, repos, phoneNumber, time.Now(), time.Minute)
+
+	invalidated, err := repos.OTPs.RecordFailedAttempt(ctx, phoneNumber, 2)
+	require.NoError(t, err)
+	assert.False(t, invalidated)
+
+	otps, err := repos.OTPs.GetPendingOTPs(ctx, phoneNumber, 5)
+	require.NoError(t, err)
+	require.Len(t, otps, 1)
+	assert.Equal(t, 1, otps[0].FailedAttempts)
+
+	invalidated, err = repos.OTPs.RecordFailedAttempt(ctx, phoneNumber, 2)
+	require.NoError(t, err)
+	assert.True(t, invalidated)
+
+	otps, err = repos.OTPs.GetPendingOTPs(ctx, phoneNumber, 5)
+	require.NoError(t, err)
+	assert.Empty(t, otps)
+
+	// Nothing left to count against
+	invalidated, err = repos.OTPs.RecordFailedAttempt(ctx, phoneNumber, 2)
+	require.NoError(t, err)
+	assert.False(t, invalidated)
+}
+
+func testOTPDeleteExpired(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+
+	// Far in the past, so rows of concurrently running tests are left alone
+	createOTP(t, repos, phoneNumber, time.Now().Add(-4*time.Hour), -3*time.Hour)
+	live := createOTP(t, repos, phoneNumber, time.Now(), time.Minute)
+
+	deleted, err := repos.OTPs.DeleteExpired(ctx, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	if !repos.NativeExpiry {
+		assert.GreaterOrEqual(t, deleted, int64(1))
+	}
+
+	otps, err := repos.OTPs.GetPendingOTPs(ctx, phoneNumber, 5)
+	require.NoError(t, err)
+	require.Len(t, otps, 1)
+	assert.Equal(t, live.ID, otps[0].ID)
+}
+
+func testOTPAttempts(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+	now := time.Now()
+
+	for _, at := range []time.Time{now.Add(-3 * time.Hour), now.Add(-time.Minute), now} {
+		attempt := &models.OTPAttempt{PhoneNumber: phoneNumber, AttemptTime: at}
+		require.NoError(t, repos.OTPAttempts.Create(ctx, attempt))
+		assert.NotEqual(t, uuid.Nil, attempt.ID)
+	}
+	require.NoError(t, repos.OTPAttempts.Create(ctx, &models.OTPAttempt{PhoneNumber: phonePrefix() + "02"}))
+
+	count, err := repos.OTPAttempts.CountRecentAttempts(ctx, phoneNumber, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	deleted, err := repos.OTPAttempts.DeleteOldAttempts(ctx, now.Add(-2*time.Hour))
+	require.NoError(t, err)
+	if !repos.NativeExpiry {
+		assert.GreaterOrEqual(t, deleted, int64(1))
+	}
+
+	count, err = repos.OTPAttempts.CountRecentAttempts(ctx, phoneNumber, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func testOTPLockouts(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+
+	lockout, err := repos.OTPLockouts.Get(ctx, phoneNumber)
+	require.NoError(t, err)
+	assert.Equal(t, phoneNumber, lockout.PhoneNumber)
+	assert.Equal(t, 0, lockout.Level)
+	assert.False(t, lockout.IsLocked())
+
+	lockout.Level = 1
+	lockout.LockedUntil = time.Now().Add(time.Minute)
+	require.NoError(t, repos.OTPLockouts.Save(ctx, lockout))
+
+	lockout.Level = 2
+	require.NoError(t, repos.OTPLockouts.Save(ctx, lockout))
+
+	stored, err := repos.OTPLockouts.Get(ctx, phoneNumber)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.Level)
+	assert.True(t, stored.IsLocked())
+
+	stale := &models.OTPLockout{PhoneNumber: phonePrefix() + "02", Level: 1, LockedUntil: time.Now().Add(-3 * time.Hour)}
+	require.NoError(t, repos.OTPLockouts.Save(ctx, stale))
+
+	deleted, err := repos.OTPLockouts.DeleteStale(ctx, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, deleted, int64(1))
+
+	stored, err = repos.OTPLockouts.Get(ctx, stale.PhoneNumber)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.Level, "stale lockouts are removed")
+
+	require.NoError(t, repos.OTPLockouts.Delete(ctx, phoneNumber))
+	stored, err = repos.OTPLockouts.Get(ctx, phoneNumber)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.Level)
+}
+
+func testTransactionCommit(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+	otp := createOTP(t, repos, phoneNumber, time.Now(), time.Minute)
+
+	err := repos.Transactor.WithinTransaction(ctx, func(tx interfaces.TxRepositories) error {
+		if _, err := tx.OTPs.Consume(ctx, otp.ID); err != nil {
+			return err
+		}
+		_, _, err := tx.Users.FindOrCreateByPhoneNumber(ctx, phoneNumber)
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = repos.Users.GetByPhoneNumber(ctx, phoneNumber)
+	assert.NoError(t, err)
+
+	otps, err := repos.OTPs.GetPendingOTPs(ctx, phoneNumber, 5)
+	require.NoError(t, err)
+	assert.Empty(t, otps)
+}
+
+func testTransactionRollback(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	phoneNumber := phonePrefix() + "01"
+	otp := createOTP(t, repos, phoneNumber, time.Now(), time.Minute)
+	failure := errors.New("fail the transaction")
+
+	err := repos.Transactor.WithinTransaction(ctx, func(tx interfaces.TxRepositories) error {
+		consumed, err := tx.OTPs.Consume(ctx, otp.ID)
+		require.NoError(t, err)
+		require.True(t, consumed)
+
+		_, _, err = tx.Users.FindOrCreateByPhoneNumber(ctx, phoneNumber)
+		require.NoError(t, err)
+
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	_, err = repos.Users.GetByPhoneNumber(ctx, phoneNumber)
+	assert.ErrorIs(t, err, utils.ErrUserNotFound, "the user insert is rolled back")
+
+	otps, err := repos.OTPs.GetPendingOTPs(ctx, phoneNumber, 5)
+	require.NoError(t, err)
+	require.Len(t, otps, 1, "the OTP is pending again")
+	assert.Equal(t, otp.ID, otps[0].ID)
+}
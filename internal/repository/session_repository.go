@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+	"go-auth/pkg/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) interfaces.SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *models.Session) error {
+	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
+		utils.LogDatabaseOperation(ctx, "create", "sessions", false, err.Error())
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	utils.LogDatabaseOperation(ctx, "create", "sessions", true, "")
+	return nil
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrSessionNotFound
+		}
+		utils.LogDatabaseOperation(ctx, "find", "sessions", false, err.Error())
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &session, nil
+}
+
+func (r *sessionRepository) ListActiveForUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "find", "sessions", false, err.Error())
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Rotate swaps the refresh token hash only if currentHash is still the
+// active one, so two concurrent refreshes with the same token cannot both win.
+// currentHash is kept as the previous hash to recognize its reuse.
+func (r *sessionRepository) Rotate(ctx context.Context, id uuid.UUID, currentHash, newHash string, expiresAt time.Time, metadata models.SessionMetadata) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, currentHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":          newHash,
+			"previous_refresh_token_hash": currentHash,
+			"expires_at":                  expiresAt,
+			"user_agent":                  metadata.UserAgent,
+			"client_ip":                   metadata.ClientIP,
+			"last_seen_at":                time.Now(),
+		})
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "update", "sessions", false, result.Error.Error())
+		return false, fmt.Errorf("failed to rotate session: %w", result.Error)
+	}
+
+	utils.LogDatabaseOperation(ctx, "update", "sessions", true, "")
+	return result.RowsAffected == 1, nil
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", id).
+		UpdateColumn("last_seen_at", lastSeenAt).Error
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "update", "sessions", false, err.Error())
+		return fmt.Errorf("failed to update session last seen: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "update", "sessions", false, result.Error.Error())
+		return fmt.Errorf("failed to revoke session: %w", result.Error)
+	}
+
+	utils.LogDatabaseOperation(ctx, "update", "sessions", true, "")
+	return nil
+}
+
+func (r *sessionRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "update", "sessions", false, result.Error.Error())
+		return fmt.Errorf("failed to revoke user sessions: %w", result.Error)
+	}
+
+	utils.LogDatabaseOperation(ctx, "update", "sessions", true, "")
+	return nil
+}
+
+func (r *sessionRepository) RevokeAllForUserExcept(ctx context.Context, userID, keepID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "update", "sessions", false, result.Error.Error())
+		return fmt.Errorf("failed to revoke user sessions: %w", result.Error)
+	}
+
+	utils.LogDatabaseOperation(ctx, "update", "sessions", true, "")
+	return nil
+}
+
+// DeleteInactive removes sessions that expired or were revoked before the
+// given time
+func (r *sessionRepository) DeleteInactive(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ? OR revoked_at < ?", before, before).Delete(&models.Session{})
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "cleanup", "sessions", false, result.Error.Error())
+		return 0, fmt.Errorf("failed to cleanup sessions: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRevokedTokenRepository(db *gorm.DB) interfaces.RevokedTokenRepository {
+	return &revokedTokenRepository{db: db}
+}
+
+func (r *revokedTokenRepository) Create(ctx context.Context, token *models.RevokedToken) error {
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error; err != nil {
+		utils.LogDatabaseOperation(ctx, "create", "revoked_tokens", false, err.Error())
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	utils.LogDatabaseOperation(ctx, "create", "revoked_tokens", true, "")
+	return nil
+}
+
+func (r *revokedTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+
+	if err != nil {
+		utils.LogDatabaseOperation(ctx, "count", "revoked_tokens", false, err.Error())
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// DeleteExpired removes blacklist entries for tokens that have expired anyway
+func (r *revokedTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.RevokedToken{})
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "cleanup", "revoked_tokens", false, result.Error.Error())
+		return 0, fmt.Errorf("failed to cleanup revoked tokens: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
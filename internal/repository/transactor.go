@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-auth/internal/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type transactor struct {
+	db      *gorm.DB
+	timeout time.Duration
+}
+
+// NewTransactor runs transactions on db. A positive timeout bounds each
+// transaction as a whole, so row locks are not held past it.
+func NewTransactor(db *gorm.DB, timeout time.Duration) interfaces.Transactor {
+	return &transactor{db: db, timeout: timeout}
+}
+
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(repos interfaces.TxRepositories) error) error {
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(interfaces.TxRepositories{
+			OTPs:        NewOTPRepository(tx),
+			OTPLockouts: NewOTPLockoutRepository(tx),
+			Users:       NewUserRepository(tx),
+		})
+	})
+}
+
+type externalOTPTransactor struct {
+	interfaces.Transactor
+	otps interfaces.OTPRepository
+}
+
+// NewTransactorWithOTPs is NewTransactor for OTPs kept outside the database,
+// e.g. in Redis. OTP writes take effect immediately and are not rolled back
+// with the transaction: a code consumed by a failed transaction stays used.
+func NewTransactorWithOTPs(db *gorm.DB, timeout time.Duration, otps interfaces.OTPRepository) interfaces.Transactor {
+	return &externalOTPTransactor{Transactor: NewTransactor(db, timeout), otps: otps}
+}
+
+func (t *externalOTPTransactor) WithinTransaction(ctx context.Context, fn func(repos interfaces.TxRepositories) error) error {
+	return t.Transactor.WithinTransaction(ctx, func(repos interfaces.TxRepositories) error {
+		repos.OTPs = t.otps
+		return fn(repos)
+	})
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 
 	"go-auth/internal/interfaces"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepository struct {
@@ -19,85 +21,148 @@ func NewUserRepository(db *gorm.DB) interfaces.UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(user *models.User) error {
-	if err := r.db.Create(user).Error; err != nil {
-		utils.LogDatabaseOperation("create", "users", false, err.Error())
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		utils.LogDatabaseOperation(ctx, "create", "users", false, err.Error())
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
-	utils.LogDatabaseOperation("create", "users", true, "")
+	utils.LogDatabaseOperation(ctx, "create", "users", true, "")
 	return nil
 }
 
-func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("id = ?", id).First(&user).Error
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, utils.ErrUserNotFound
 		}
-		utils.LogDatabaseOperation("find", "users", false, err.Error())
+		utils.LogDatabaseOperation(ctx, "find", "users", false, err.Error())
 		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
 
 	return &user, nil
 }
 
-func (r *userRepository) GetByPhoneNumber(phoneNumber string) (*models.User, error) {
+func (r *userRepository) GetByPhoneNumber(ctx context.Context, phoneNumber string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("phone_number = ?", phoneNumber).First(&user).Error
+	err := r.db.WithContext(ctx).Where("phone_number = ?", phoneNumber).First(&user).Error
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, utils.ErrUserNotFound
 		}
-		utils.LogDatabaseOperation("find", "users", false, err.Error())
+		utils.LogDatabaseOperation(ctx, "find", "users", false, err.Error())
 		return nil, fmt.Errorf("failed to get user by phone: %w", err)
 	}
 
 	return &user, nil
 }
 
-func (r *userRepository) GetUsers(page, limit int, search string) ([]models.User, int64, error) {
+// FindOrCreateByPhoneNumber inserts the user unless the phone number is
+// already registered. Concurrent first logins for the same number end up with
+// the same user instead of a unique index violation.
+func (r *userRepository) FindOrCreateByPhoneNumber(ctx context.Context, phoneNumber string) (*models.User, bool, error) {
+	user := &models.User{PhoneNumber: phoneNumber}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "phone_number"}},
+		DoNothing: true,
+	}).Create(user)
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "upsert", "users", false, result.Error.Error())
+		return nil, false, fmt.Errorf("failed to create user: %w", result.Error)
+	}
+
+	if result.RowsAffected == 1 {
+		utils.LogDatabaseOperation(ctx, "upsert", "users", true, "")
+		return user, true, nil
+	}
+
+	existing, err := r.GetByPhoneNumber(ctx, phoneNumber)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return existing, false, nil
+}
+
+func (r *userRepository) GetUsers(ctx context.Context, page, limit int, search string) ([]models.User, int64, error) {
 	var users []models.User
 	var total int64
 
-	query := r.db.Model(&models.User{})
+	query := r.db.WithContext(ctx).Model(&models.User{})
 
 	if search != "" {
-		query = query.Where("phone_number ILIKE ?", "%"+search+"%")
+		// Phone numbers have no letters, so LIKE needs no case folding and
+		// works on every driver
+		query = query.Where("phone_number LIKE ?", "%"+search+"%")
 	}
 
 	if err := query.Count(&total).Error; err != nil {
-		utils.LogDatabaseOperation("count", "users", false, err.Error())
+		utils.LogDatabaseOperation(ctx, "count", "users", false, err.Error())
 		return nil, 0, fmt.Errorf("failed to count users: %w", err)
 	}
 
 	offset := (page - 1) * limit
 	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error; err != nil {
-		utils.LogDatabaseOperation("find", "users", false, err.Error())
+		utils.LogDatabaseOperation(ctx, "find", "users", false, err.Error())
 		return nil, 0, fmt.Errorf("failed to get users: %w", err)
 	}
 
 	return users, total, nil
 }
 
-func (r *userRepository) Update(user *models.User) error {
-	if err := r.db.Save(user).Error; err != nil {
-		utils.LogDatabaseOperation("update", "users", false, err.Error())
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	if err := r.db.WithContext(ctx).Save(user).Error; err != nil {
+		utils.LogDatabaseOperation(ctx, "update", "users", false, err.Error())
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
-	utils.LogDatabaseOperation("update", "users", true, "")
+	utils.LogDatabaseOperation(ctx, "update", "users", true, "")
+	return nil
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id uuid.UUID, role models.Role) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("role", role)
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "update", "users", false, result.Error.Error())
+		return fmt.Errorf("failed to update user role: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return utils.ErrUserNotFound
+	}
+
+	utils.LogDatabaseOperation(ctx, "update", "users", true, "")
+	return nil
+}
+
+func (r *userRepository) IncrementTokenVersion(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1"))
+
+	if result.Error != nil {
+		utils.LogDatabaseOperation(ctx, "update", "users", false, result.Error.Error())
+		return fmt.Errorf("failed to increment token version: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return utils.ErrUserNotFound
+	}
+
+	utils.LogDatabaseOperation(ctx, "update", "users", true, "")
 	return nil
 }
 
-func (r *userRepository) Delete(id uuid.UUID) error {
-	result := r.db.Delete(&models.User{}, id)
+func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Delete(&models.User{}, id)
 
 	if result.Error != nil {
-		utils.LogDatabaseOperation("delete", "users", false, result.Error.Error())
+		utils.LogDatabaseOperation(ctx, "delete", "users", false, result.Error.Error())
 		return fmt.Errorf("failed to delete user: %w", result.Error)
 	}
 
@@ -105,6 +170,6 @@ func (r *userRepository) Delete(id uuid.UUID) error {
 		return utils.ErrUserNotFound
 	}
 
-	utils.LogDatabaseOperation("delete", "users", true, "")
+	utils.LogDatabaseOperation(ctx, "delete", "users", true, "")
 	return nil
 }
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// Locker elects a leader per job across replicas
+type Locker interface {
+	// TryAcquire takes the lease for name without blocking. acquired is false
+	// when another replica holds it.
+	TryAcquire(ctx context.Context, name string) (lease Lease, acquired bool, err error)
+}
+
+// Lease is held by the leader of a job until released or lost
+type Lease interface {
+	Alive(ctx context.Context) bool
+	Release(ctx context.Context) error
+}
+
+// PostgresLocker elects leaders with session-level advisory locks. Each lease
+// pins one pooled connection for as long as it is held, so the pool needs
+// one connection per job on top of what requests use. The lock is dropped by
+// Postgres if the connection or the replica dies, letting another replica
+// take over.
+type PostgresLocker struct {
+	db *sql.DB
+}
+
+func NewPostgresLocker(db *sql.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+func (l *PostgresLocker) TryAcquire(ctx context.Context, name string) (Lease, bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for job lock: %w", err)
+	}
+
+	key := advisoryLockKey(name)
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire job lock: %w", err)
+	}
+
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	return &postgresLease{conn: conn, key: key}, true, nil
+}
+
+type postgresLease struct {
+	conn *sql.Conn
+	key  int64
+}
+
+func (l *postgresLease) Alive(ctx context.Context) bool {
+	return l.conn.PingContext(ctx) == nil
+}
+
+func (l *postgresLease) Release(ctx context.Context) error {
+	defer l.conn.Close()
+
+	if _, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		return fmt.Errorf("failed to release job lock: %w", err)
+	}
+
+	return nil
+}
+
+// advisoryLockKey maps a job name onto the bigint advisory lock space
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("go-auth:job:" + name))
+	return int64(h.Sum64())
+}
+
+// LocalLocker grants each job to one holder within this process. It is only
+// correct with a single replica, such as a development server on SQLite.
+type LocalLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{held: map[string]bool{}}
+}
+
+func (l *LocalLocker) TryAcquire(ctx context.Context, name string) (Lease, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held[name] {
+		return nil, false, nil
+	}
+	l.held[name] = true
+	return &localLease{locker: l, name: name}, true, nil
+}
+
+type localLease struct {
+	locker *LocalLocker
+	name   string
+}
+
+func (l *localLease) Alive(ctx context.Context) bool { return true }
+
+func (l *localLease) Release(ctx context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	delete(l.locker.held, l.name)
+	return nil
+}
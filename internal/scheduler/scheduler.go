@@ -0,0 +1,225 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-auth/internal/metrics"
+	"go-auth/pkg/utils"
+)
+
+// Job is a periodic task. Run returns the number of items it processed, e.g.
+// rows deleted, which is recorded in the job's stats.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration
+	Run      func(ctx context.Context) (int64, error)
+}
+
+// JobStats describes a job's recent activity on this replica
+type JobStats struct {
+	Name           string     `json:"name"`
+	Interval       string     `json:"interval"`
+	Leader         bool       `json:"leader"`
+	Runs           int64      `json:"runs"`
+	Failures       int64      `json:"failures"`
+	ItemsProcessed int64      `json:"items_processed"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// Scheduler runs jobs in the background. Each job is run by at most one
+// replica at a time: the replica holding the job's leader lease.
+type Scheduler struct {
+	locker Locker
+	jobs   []*jobRunner
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type jobRunner struct {
+	job   Job
+	mu    sync.Mutex
+	lease Lease
+	stats JobStats
+}
+
+func New(locker Locker) *Scheduler {
+	return &Scheduler{locker: locker}
+}
+
+// Register adds a job. Jobs must be registered before Start.
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, &jobRunner{
+		job:   job,
+		stats: JobStats{Name: job.Name, Interval: job.Interval.String()},
+	})
+	metrics.RegisterJob(job.Name)
+}
+
+// Jobs returns the number of registered jobs. With a PostgresLocker, each one
+// the replica leads pins a pooled connection.
+func (s *Scheduler) Jobs() int {
+	return len(s.jobs)
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, runner := range s.jobs {
+		s.wg.Add(1)
+		go func(runner *jobRunner) {
+			defer s.wg.Done()
+			s.loop(ctx, runner)
+		}(runner)
+	}
+
+	utils.LogWithFields(map[string]interface{}{
+		"jobs": len(s.jobs),
+		"type": "scheduler",
+	}).Info("Scheduler started")
+}
+
+// Stop cancels running jobs, releases leader leases and waits for the job
+// goroutines to exit
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) Stats() []JobStats {
+	stats := make([]JobStats, len(s.jobs))
+	for i, runner := range s.jobs {
+		runner.mu.Lock()
+		stats[i] = runner.stats
+		runner.mu.Unlock()
+	}
+	return stats
+}
+
+func (s *Scheduler) loop(ctx context.Context, runner *jobRunner) {
+	defer s.release(runner)
+
+	ticker := time.NewTicker(runner.job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if s.lead(ctx, runner) {
+			s.run(ctx, runner)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead reports whether this replica holds the job's lease, trying to take it
+// over when it is free
+func (s *Scheduler) lead(ctx context.Context, runner *jobRunner) bool {
+	if runner.lease != nil {
+		if runner.lease.Alive(ctx) {
+			return true
+		}
+
+		utils.LogWithFields(map[string]interface{}{
+			"job":  runner.job.Name,
+			"type": "scheduler",
+		}).Warn("Lost job leadership")
+		s.release(runner)
+	}
+
+	lease, acquired, err := s.locker.TryAcquire(ctx, runner.job.Name)
+	if err != nil {
+		if ctx.Err() == nil {
+			utils.LogError(err, "Failed to acquire job lease", map[string]interface{}{"job": runner.job.Name})
+		}
+		return false
+	}
+	if !acquired {
+		return false
+	}
+
+	runner.lease = lease
+	runner.setLeader(true)
+	utils.LogWithFields(map[string]interface{}{
+		"job":  runner.job.Name,
+		"type": "scheduler",
+	}).Info("Acquired job leadership")
+
+	return true
+}
+
+func (s *Scheduler) release(runner *jobRunner) {
+	if runner.lease == nil {
+		return
+	}
+
+	// The job context may already be cancelled during shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := runner.lease.Release(ctx); err != nil {
+		utils.LogError(err, "Failed to release job lease", map[string]interface{}{"job": runner.job.Name})
+	}
+
+	runner.lease = nil
+	runner.setLeader(false)
+}
+
+func (s *Scheduler) run(ctx context.Context, runner *jobRunner) {
+	// Tag everything the job logs, down to its queries, with the job name
+	runCtx := utils.WithLogFields(ctx, map[string]interface{}{"job": runner.job.Name})
+	if runner.job.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(runCtx, runner.job.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	items, err := runner.job.Run(runCtx)
+	duration := time.Since(start)
+
+	runner.mu.Lock()
+	runner.stats.Runs++
+	runner.stats.LastRunAt = &start
+	runner.stats.LastDurationMs = duration.Milliseconds()
+	runner.stats.ItemsProcessed += items
+	runner.stats.LastError = ""
+	if err != nil {
+		runner.stats.Failures++
+		runner.stats.LastError = err.Error()
+	}
+	runner.mu.Unlock()
+
+	metrics.ObserveJobRun(runner.job.Name, err == nil, duration, start.Add(duration))
+
+	fields := map[string]interface{}{
+		"job":         runner.job.Name,
+		"items":       items,
+		"duration_ms": duration.Milliseconds(),
+		"type":        "scheduler",
+	}
+
+	if err != nil {
+		utils.LogError(err, "Scheduled job failed", fields)
+		return
+	}
+
+	utils.LogWithFields(fields).Info("Scheduled job completed")
+}
+
+func (r *jobRunner) setLeader(leader bool) {
+	r.mu.Lock()
+	r.stats.Leader = leader
+	r.mu.Unlock()
+}
@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-auth/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogger()
+	utils.Logger.SetLevel(logrus.PanicLevel)
+	os.Exit(m.Run())
+}
+
+func TestOnlyOneReplicaRunsAJob(t *testing.T) {
+	locker := NewLocalLocker()
+	var runs atomic.Int64
+
+	job := Job{
+		Name:     "cleanup",
+		Interval: 10 * time.Millisecond,
+		Run: func(ctx context.Context) (int64, error) {
+			runs.Add(1)
+			return 2, nil
+		},
+	}
+
+	first, second := New(locker), New(locker)
+	first.Register(job)
+	second.Register(job)
+
+	first.Start(context.Background())
+	require.Eventually(t, func() bool { return first.Stats()[0].Leader }, time.Second, time.Millisecond)
+	second.Start(context.Background())
+
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+
+	assert.False(t, second.Stats()[0].Leader)
+	assert.Zero(t, second.Stats()[0].Runs)
+
+	stats := first.Stats()[0]
+	assert.Equal(t, "cleanup", stats.Name)
+	assert.NotNil(t, stats.LastRunAt)
+	assert.Equal(t, stats.Runs*2, stats.ItemsProcessed)
+
+	// The standby replica takes over once the leader stops
+	first.Stop()
+	require.Eventually(t, func() bool { return second.Stats()[0].Runs > 0 }, time.Second, time.Millisecond)
+	assert.True(t, second.Stats()[0].Leader)
+	assert.False(t, first.Stats()[0].Leader)
+
+	second.Stop()
+}
+
+func TestJobFailuresAndTimeouts(t *testing.T) {
+	s := New(NewLocalLocker())
+	s.Register(Job{
+		Name:     "failing",
+		Interval: 10 * time.Millisecond,
+		Timeout:  time.Millisecond,
+		Run: func(ctx context.Context) (int64, error) {
+			<-ctx.Done()
+			return 0, errors.New("gave up: " + ctx.Err().Error())
+		},
+	})
+
+	s.Start(context.Background())
+	require.Eventually(t, func() bool { return s.Stats()[0].Failures >= 2 }, time.Second, time.Millisecond)
+	s.Stop()
+
+	stats := s.Stats()[0]
+	assert.Equal(t, stats.Runs, stats.Failures)
+	assert.Contains(t, stats.LastError, "deadline exceeded")
+}
+
+func TestAdvisoryLockKeyIsStable(t *testing.T) {
+	assert.Equal(t, advisoryLockKey("otp_cleanup"), advisoryLockKey("otp_cleanup"))
+	assert.NotEqual(t, advisoryLockKey("otp_cleanup"), advisoryLockKey("session_cleanup"))
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"go-auth/internal/config"
+	"go-auth/pkg/utils"
+)
+
+// Server is an http.Server with timeouts, optional hot-reloaded TLS and
+// graceful shutdown
+type Server struct {
+	httpServer *http.Server
+	config     *config.ServerConfig
+	certs      *CertReloader
+}
+
+func New(cfg *config.ServerConfig, addr string, handler http.Handler) (*Server, error) {
+	s := &Server{
+		config: cfg,
+		httpServer: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+	}
+
+	if cfg.TLSCertFile != "" {
+		certs, err := NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		s.certs = certs
+		s.httpServer.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+
+	return s, nil
+}
+
+// Certificates returns the TLS certificate reloader, or nil without TLS
+func (s *Server) Certificates() *CertReloader {
+	return s.certs
+}
+
+// Run serves until ctx is cancelled, then stops accepting connections and
+// waits up to the shutdown timeout for requests in flight to finish
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+
+	return s.Serve(ctx, listener)
+}
+
+// Serve is Run on an existing listener
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+
+	go func() {
+		var err error
+		if s.certs != nil {
+			if s.config.TLSReloadInterval > 0 {
+				watchCtx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				go s.certs.Watch(watchCtx, s.config.TLSReloadInterval)
+			}
+
+			err = s.httpServer.ServeTLS(listener, "", "")
+		} else {
+			err = s.httpServer.Serve(listener)
+		}
+		serveErr <- err
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	utils.Logger.Info("Shutting down HTTP server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to drain HTTP server: %w", err)
+	}
+
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+	"go-auth/internal/metrics"
+	"go-auth/internal/models"
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/utils"
+)
+
+// ChallengeGate asks for a solved challenge on send-otp once a client IP or
+// a country calling code sends more requests per hour than its soft
+// threshold. Requests below the thresholds pass without one, so real users
+// only see a challenge while something looks wrong.
+type ChallengeGate struct {
+	verifier  interfaces.ChallengeVerifier
+	byIP      ratelimit.Limiter
+	byCountry ratelimit.Limiter
+}
+
+// NewChallengeGate counts requests in store. A nil verifier lets every
+// request through.
+func NewChallengeGate(cfg *config.ChallengeConfig, verifier interfaces.ChallengeVerifier, store ratelimit.Store) *ChallengeGate {
+	g := &ChallengeGate{verifier: verifier}
+	if !cfg.Enabled || verifier == nil {
+		g.verifier = nil
+		return g
+	}
+
+	if cfg.IPHourly > 0 {
+		g.byIP = ratelimit.NewSlidingWindow(store, "challenge:ip", cfg.IPHourly, time.Hour)
+	}
+	if cfg.CountryHourly > 0 {
+		g.byCountry = ratelimit.NewSlidingWindow(store, "challenge:country", cfg.CountryHourly, time.Hour)
+	}
+
+	return g
+}
+
+// Check lets a send-otp request through when it is below the thresholds, or
+// carries a solution to a challenge. Otherwise it returns ErrChallengeRequired
+// or ErrChallengeFailed, with a new challenge for the client to solve.
+// Invalid phone numbers are let through uncounted for SendOTP to reject. A
+// request over a threshold is refused with ErrChallengeUnavailable if the
+// verifier fails, as letting it through would let any solution pass while
+// the CAPTCHA provider is down or spent PoW tokens cannot be recorded.
+func (g *ChallengeGate) Check(ctx context.Context, phoneNumber, clientIP, solution string) (*models.Challenge, error) {
+	if g.verifier == nil {
+		return nil, nil
+	}
+
+	if validationErrors := utils.ValidatePhoneNumber(phoneNumber); validationErrors.HasErrors() {
+		return nil, nil
+	}
+	phoneNumber = utils.NormalizePhoneNumber(phoneNumber)
+
+	reason := g.overThreshold(ctx, phoneNumber, clientIP)
+	if reason == "" {
+		return nil, nil
+	}
+
+	appErr, outcome, event := utils.ErrChallengeRequired, metrics.SendChallengeRequired, "otp_challenge_required"
+	if solution != "" {
+		solved, err := g.verifier.Verify(ctx, solution, clientIP)
+		if err != nil {
+			return nil, g.unavailable(ctx, err)
+		}
+		if solved {
+			return nil, nil
+		}
+
+		appErr, outcome, event = utils.ErrChallengeFailed, metrics.SendChallengeFailed, "otp_challenge_failed"
+	}
+
+	challenge, err := g.verifier.Issue(ctx)
+	if err != nil {
+		return nil, g.unavailable(ctx, err)
+	}
+
+	metrics.ObserveOTPSend(outcome)
+	utils.LogSecurityEvent(ctx, event, "", phoneNumber, reason)
+	return challenge, appErr
+}
+
+// unavailable refuses a request the verifier failed on
+func (g *ChallengeGate) unavailable(ctx context.Context, err error) error {
+	metrics.ObserveOTPSend(metrics.SendChallengeUnavailable)
+	utils.LogWithContext(ctx).WithError(err).Error("Challenge verifier unavailable")
+	return utils.ErrChallengeUnavailable
+}
+
+// overThreshold counts the request and returns which threshold it is over,
+// if any. The thresholds are soft, so an unreachable store lets the request
+// through; the fraud guard's budgets still apply.
+func (g *ChallengeGate) overThreshold(ctx context.Context, phoneNumber, clientIP string) string {
+	if g.byIP != nil && clientIP != "" {
+		if reason := g.count(ctx, g.byIP, clientIP, "client IP"); reason != "" {
+			return reason
+		}
+	}
+
+	if g.byCountry != nil {
+		code := utils.CountryCallingCode(phoneNumber)
+		if reason := g.count(ctx, g.byCountry, code, "destination "+code); reason != "" {
+			return reason
+		}
+	}
+
+	return ""
+}
+
+func (g *ChallengeGate) count(ctx context.Context, limiter ratelimit.Limiter, key, subject string) string {
+	result, err := limiter.Allow(ctx, key)
+	if err != nil {
+		utils.LogWithContext(ctx).WithError(err).Error("Challenge threshold unavailable")
+		return ""
+	}
+
+	if result.Allowed {
+		return ""
+	}
+	return fmt.Sprintf("%s over %d send-otp requests per hour", subject, result.Limit)
+}
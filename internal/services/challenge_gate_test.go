@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-auth/internal/config"
+	"go-auth/internal/models"
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVerifier accepts the solution "solved"
+type fakeVerifier struct{}
+
+func (fakeVerifier) Issue(ctx context.Context) (*models.Challenge, error) {
+	return &models.Challenge{Type: "pow", Token: "token", Difficulty: 1}, nil
+}
+
+func (fakeVerifier) Verify(ctx context.Context, solution, clientIP string) (bool, error) {
+	return solution == "solved", nil
+}
+
+// unreachableVerifier fails like a CAPTCHA provider that cannot be reached
+type unreachableVerifier struct{}
+
+func (unreachableVerifier) Issue(ctx context.Context) (*models.Challenge, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (unreachableVerifier) Verify(ctx context.Context, solution, clientIP string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func newChallengeGate(t *testing.T, cfg config.ChallengeConfig) *ChallengeGate {
+	t.Helper()
+
+	if utils.Logger == nil {
+		utils.InitLogger()
+		utils.Logger.SetLevel(logrus.PanicLevel)
+	}
+
+	cfg.Enabled = true
+	return NewChallengeGate(&cfg, fakeVerifier{}, ratelimit.NewMemoryStore())
+}
+
+func TestChallengeGateAsksOverTheIPThreshold(t *testing.T) {
+	ctx := context.Background()
+	gate := newChallengeGate(t, config.ChallengeConfig{IPHourly: 2})
+
+	for i := 0; i < 2; i++ {
+		challenge, err := gate.Check(ctx, "+15551234567", "192.0.2.1", "")
+		require.NoError(t, err)
+		assert.Nil(t, challenge)
+	}
+
+	challenge, err := gate.Check(ctx, "+15551234567", "192.0.2.1", "")
+	assert.ErrorIs(t, err, utils.ErrChallengeRequired)
+	require.NotNil(t, challenge)
+	assert.Equal(t, "token", challenge.Token)
+
+	challenge, err = gate.Check(ctx, "+15551234567", "192.0.2.1", "wrong")
+	assert.ErrorIs(t, err, utils.ErrChallengeFailed)
+	assert.NotNil(t, challenge, "a failed attempt gets a new challenge")
+
+	challenge, err = gate.Check(ctx, "+15551234567", "192.0.2.1", "solved")
+	assert.NoError(t, err)
+	assert.Nil(t, challenge)
+
+	_, err = gate.Check(ctx, "+15551234567", "192.0.2.2", "")
+	assert.NoError(t, err, "other clients are below the threshold")
+}
+
+func TestChallengeGateAsksOverTheCountryThreshold(t *testing.T) {
+	ctx := context.Background()
+	gate := newChallengeGate(t, config.ChallengeConfig{CountryHourly: 1})
+
+	_, err := gate.Check(ctx, "+882123456789", "192.0.2.1", "")
+	require.NoError(t, err)
+
+	_, err = gate.Check(ctx, "+882987654321", "192.0.2.2", "")
+	assert.ErrorIs(t, err, utils.ErrChallengeRequired)
+
+	_, err = gate.Check(ctx, "+15551234567", "192.0.2.2", "")
+	assert.NoError(t, err)
+}
+
+func TestChallengeGateDisabled(t *testing.T) {
+	gate := NewChallengeGate(&config.ChallengeConfig{IPHourly: 1}, fakeVerifier{}, ratelimit.NewMemoryStore())
+
+	for i := 0; i < 3; i++ {
+		_, err := gate.Check(context.Background(), "+15551234567", "192.0.2.1", "")
+		assert.NoError(t, err)
+	}
+}
+
+func TestChallengeGateSkipsInvalidPhoneNumbers(t *testing.T) {
+	ctx := context.Background()
+	gate := newChallengeGate(t, config.ChallengeConfig{IPHourly: 1, CountryHourly: 1})
+
+	for i := 0; i < 3; i++ {
+		_, err := gate.Check(ctx, "not-a-number", "192.0.2.1", "")
+		assert.NoError(t, err, "left for SendOTP to reject")
+	}
+
+	_, err := gate.Check(ctx, "+15551234567", "192.0.2.1", "")
+	assert.NoError(t, err, "invalid numbers do not count towards the thresholds")
+
+	_, err = gate.Check(ctx, "15559876543", "192.0.2.2", "")
+	assert.ErrorIs(t, err, utils.ErrChallengeRequired, "numbers are counted with or without their +")
+}
+
+func TestChallengeGateFailsClosedWhenTheVerifierIsUnreachable(t *testing.T) {
+	ctx := context.Background()
+	gate := newChallengeGate(t, config.ChallengeConfig{IPHourly: 1})
+	gate.verifier = unreachableVerifier{}
+
+	_, err := gate.Check(ctx, "+15551234567", "192.0.2.1", "")
+	assert.NoError(t, err, "below the threshold the verifier is not needed")
+
+	// Issuing a challenge fails
+	challenge, err := gate.Check(ctx, "+15551234567", "192.0.2.1", "")
+	assert.ErrorIs(t, err, utils.ErrChallengeUnavailable)
+	assert.Nil(t, challenge)
+
+	// Checking a solution fails
+	challenge, err = gate.Check(ctx, "+15551234567", "192.0.2.1", "solved")
+	assert.ErrorIs(t, err, utils.ErrChallengeUnavailable)
+	assert.Nil(t, challenge)
+}
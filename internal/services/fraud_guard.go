@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/utils"
+)
+
+// numberRangeDigits is how many trailing digits a number range spans.
+// Pumping campaigns cycle through consecutive numbers leased from one
+// premium-rate operator, so a range of 1000 sees traffic no real user base
+// produces.
+const numberRangeDigits = 3
+
+// FraudGuard decides whether a code may be sent to a destination. It
+// refuses denied prefixes, spends hourly and daily send budgets, and
+// suspends a country calling code whose codes are no longer being verified:
+// pumped numbers receive codes nobody enters.
+type FraudGuard struct {
+	config  config.FraudConfig
+	store   ratelimit.Store
+	allowed []string
+	denied  []string
+	budgets []sendBudget
+	now     func() time.Time
+}
+
+// sendBudget is one budget, e.g. sends per country calling code per day
+type sendBudget struct {
+	scope   string
+	period  string
+	key     func(number, clientIP string) string
+	limiter *ratelimit.SlidingWindow
+}
+
+// NewFraudGuard keeps its budgets and breakers in store
+func NewFraudGuard(cfg config.FraudConfig, store ratelimit.Store) *FraudGuard {
+	g := &FraudGuard{config: cfg, store: store, now: time.Now}
+
+	for _, prefix := range cfg.AllowedPrefixes {
+		g.allowed = append(g.allowed, utils.NormalizePhoneNumber(prefix))
+	}
+	for _, prefix := range cfg.DeniedPrefixes {
+		g.denied = append(g.denied, utils.NormalizePhoneNumber(prefix))
+	}
+
+	// Narrow budgets come first, so a single abusive client or range runs
+	// out of its own budget before it spends the shared ones
+	g.addBudgets("ip", func(_, clientIP string) string { return clientIP }, cfg.IPHourly, cfg.IPDaily)
+	g.addBudgets("range", numberRange, cfg.RangeHourly, cfg.RangeDaily)
+	g.addBudgets("country", func(number, _ string) string { return utils.CountryCallingCode(number) }, cfg.CountryHourly, cfg.CountryDaily)
+	g.addBudgets("global", func(string, string) string { return "all" }, cfg.GlobalHourly, cfg.GlobalDaily)
+
+	return g
+}
+
+func (g *FraudGuard) addBudgets(scope string, key func(number, clientIP string) string, hourly, daily int) {
+	if hourly > 0 {
+		g.budgets = append(g.budgets, sendBudget{scope: scope, period: "hour", key: key,
+			limiter: ratelimit.NewSlidingWindow(g.store, "fraud:"+scope+":hour", hourly, time.Hour)})
+	}
+	if daily > 0 {
+		g.budgets = append(g.budgets, sendBudget{scope: scope, period: "day", key: key,
+			limiter: ratelimit.NewSlidingWindow(g.store, "fraud:"+scope+":day", daily, 24*time.Hour)})
+	}
+}
+
+// AllowSend checks a code to phoneNumber requested from clientIP against
+// the prefix lists, the breaker of its country calling code and the send
+// budgets, and counts it if it may go out. A refused send is not counted
+// against any of them.
+func (g *FraudGuard) AllowSend(ctx context.Context, phoneNumber, clientIP string) error {
+	if !g.config.Enabled {
+		return nil
+	}
+
+	number := utils.NormalizePhoneNumber(phoneNumber)
+	if reason := g.blocked(number); reason != "" {
+		utils.LogSecurityEvent(ctx, "sms_fraud_blocked", "", phoneNumber, reason)
+		return utils.ErrDestinationNotAllowed
+	}
+
+	now := g.now()
+	if err := g.checkBreaker(ctx, phoneNumber, number, now); err != nil {
+		return err
+	}
+
+	if err := g.spendBudgets(ctx, phoneNumber, number, clientIP); err != nil {
+		return err
+	}
+
+	g.countSend(ctx, number, now)
+	return nil
+}
+
+// spendBudgets takes the send from every budget. Budgets are counted as
+// they are checked, so those already taken are refunded when a later one
+// refuses the send.
+func (g *FraudGuard) spendBudgets(ctx context.Context, phoneNumber, number, clientIP string) error {
+	var spent []sendBudget
+	refund := func() {
+		for _, budget := range spent {
+			if err := budget.limiter.Refund(ctx, budget.key(number, clientIP)); err != nil {
+				utils.LogWithContext(ctx).WithError(err).Warn("Failed to refund an SMS send budget")
+			}
+		}
+	}
+
+	for _, budget := range g.budgets {
+		key := budget.key(number, clientIP)
+		if key == "" {
+			continue
+		}
+
+		result, err := budget.limiter.Allow(ctx, key)
+		if err != nil {
+			refund()
+			return err
+		}
+
+		if !result.Allowed {
+			refund()
+			utils.LogSecurityEvent(ctx, "sms_fraud_budget_exceeded", "", phoneNumber,
+				fmt.Sprintf("%s budget of %d codes per %s spent", budget.scope, result.Limit, budget.period))
+			return utils.ErrRateLimitExceeded
+		}
+
+		spent = append(spent, budget)
+	}
+
+	return nil
+}
+
+// RecordVerified counts a verified code towards the conversion rate of its
+// country calling code. Failures are only logged: the user is signed in
+// either way.
+func (g *FraudGuard) RecordVerified(ctx context.Context, phoneNumber string) {
+	if !g.config.Enabled || g.config.BreakerMinSends <= 0 {
+		return
+	}
+
+	now := g.now()
+	start := now.Truncate(g.config.BreakerWindow)
+	code := utils.CountryCallingCode(phoneNumber)
+	err := g.store.Update(ctx, breakerKey(code), g.breakerTTL(), func(state ratelimit.State) ratelimit.State {
+		switch {
+		case state.Time.After(now):
+			return state
+		// Codes sent late in a window are verified early in the next one,
+		// before it has sends of its own
+		case state.Time.Equal(start), state.Time.Equal(start.Add(-g.config.BreakerWindow)):
+		default:
+			state = ratelimit.State{Time: start}
+		}
+
+		state.Previous++
+		return state
+	})
+
+	if err != nil {
+		utils.LogWithContext(ctx).WithError(err).Warn("Failed to record OTP verification for the fraud breaker")
+	}
+}
+
+// blocked returns why number may not receive codes, if it may not
+func (g *FraudGuard) blocked(number string) string {
+	for _, prefix := range g.denied {
+		if strings.HasPrefix(number, prefix) {
+			return "destination " + prefix + " is denied"
+		}
+	}
+
+	if len(g.allowed) == 0 {
+		return ""
+	}
+	for _, prefix := range g.allowed {
+		if strings.HasPrefix(number, prefix) {
+			return ""
+		}
+	}
+	return "destination " + utils.CountryCallingCode(number) + " is not allowed"
+}
+
+// checkBreaker refuses a send while the breaker of the number's country
+// calling code is open. The breaker keeps sends in State.Count and
+// verifications in State.Previous for the window starting at State.Time;
+// while open, State.Time is when it closes again. A window is judged by the
+// first send after it ended, as codes take a while to be entered and judging
+// a window still in progress would count its latest codes as unverified.
+func (g *FraudGuard) checkBreaker(ctx context.Context, phoneNumber, number string, now time.Time) error {
+	if g.config.BreakerMinSends <= 0 {
+		return nil
+	}
+
+	code := utils.CountryCallingCode(number)
+	var opened bool
+	var sent, verified float64
+	var until time.Time
+	err := g.store.Update(ctx, breakerKey(code), g.breakerTTL(), func(state ratelimit.State) ratelimit.State {
+		sent, verified = state.Count, state.Previous
+		state, opened = g.rollBreaker(state, now)
+		until = state.Time
+		return state
+	})
+	if err != nil {
+		return err
+	}
+
+	if opened {
+		utils.LogSecurityEvent(ctx, "sms_fraud_breaker_opened", "", "",
+			fmt.Sprintf("%s suspended for %s: %.0f of %.0f codes verified", code, g.config.BreakerCooldown, verified, sent))
+	}
+
+	if until.After(now) {
+		utils.LogSecurityEvent(ctx, "sms_fraud_blocked", "", phoneNumber, "destination "+code+" is suspended")
+		return utils.ErrDestinationSuspended
+	}
+
+	return nil
+}
+
+// countSend counts an allowed send towards the breaker of the number's
+// country calling code, in the window checkBreaker found it in. Failures
+// are only logged: the send has passed every check by now.
+func (g *FraudGuard) countSend(ctx context.Context, number string, now time.Time) {
+	if g.config.BreakerMinSends <= 0 {
+		return
+	}
+
+	start := now.Truncate(g.config.BreakerWindow)
+	code := utils.CountryCallingCode(number)
+	err := g.store.Update(ctx, breakerKey(code), g.breakerTTL(), func(state ratelimit.State) ratelimit.State {
+		if state.Time.Equal(start) {
+			state.Count++
+		}
+		return state
+	})
+
+	if err != nil {
+		utils.LogWithContext(ctx).WithError(err).Warn("Failed to count an OTP send for the fraud breaker")
+	}
+}
+
+// rollBreaker moves the breaker on to the window containing now, opening it
+// if the window that just ended collapsed
+func (g *FraudGuard) rollBreaker(state ratelimit.State, now time.Time) (ratelimit.State, bool) {
+	start := now.Truncate(g.config.BreakerWindow)
+	switch {
+	case state.Time.After(now), state.Time.Equal(start):
+		return state, false
+	case state.Time.Equal(start.Add(-g.config.BreakerWindow)) && g.collapsed(state):
+		return ratelimit.State{Time: now.Add(g.config.BreakerCooldown)}, true
+	default:
+		return ratelimit.State{Time: start}, false
+	}
+}
+
+// collapsed reports whether enough codes were sent in a window to judge it,
+// and too few of them were verified
+func (g *FraudGuard) collapsed(state ratelimit.State) bool {
+	return state.Count >= float64(g.config.BreakerMinSends) &&
+		state.Previous < state.Count*g.config.BreakerMinConversion
+}
+
+// breakerTTL keeps a window until the one after it has been judged, or an
+// open breaker for its cooldown
+func (g *FraudGuard) breakerTTL() time.Duration {
+	return 2*g.config.BreakerWindow + g.config.BreakerCooldown
+}
+
+func breakerKey(code string) string {
+	return "fraud:breaker:" + code
+}
+
+// numberRange drops the last numberRangeDigits digits of number
+func numberRange(number, _ string) string {
+	if len(number) <= numberRangeDigits+1 {
+		return number
+	}
+	return number[:len(number)-numberRangeDigits]
+}
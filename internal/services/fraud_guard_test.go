@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFraudGuard(t *testing.T, cfg config.FraudConfig) *FraudGuard {
+	t.Helper()
+
+	if utils.Logger == nil {
+		utils.InitLogger()
+		utils.Logger.SetLevel(logrus.PanicLevel)
+	}
+
+	cfg.Enabled = true
+	return NewFraudGuard(cfg, ratelimit.NewMemoryStore())
+}
+
+func TestFraudGuardPrefixLists(t *testing.T) {
+	ctx := context.Background()
+	guard := newFraudGuard(t, config.FraudConfig{
+		AllowedPrefixes: []string{"+1", "44"},
+		DeniedPrefixes:  []string{"+1900"},
+	})
+
+	assert.NoError(t, guard.AllowSend(ctx, "+15551234567", ""))
+	assert.NoError(t, guard.AllowSend(ctx, "447700900123", ""), "prefixes match with or without +")
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+19005551234", ""), utils.ErrDestinationNotAllowed, "denied within an allowed prefix")
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+882123456789", ""), utils.ErrDestinationNotAllowed, "not on the allow list")
+}
+
+func TestFraudGuardBudgets(t *testing.T) {
+	ctx := context.Background()
+	guard := newFraudGuard(t, config.FraudConfig{RangeHourly: 2, IPDaily: 3})
+
+	// Two numbers of one range spend its budget
+	assert.NoError(t, guard.AllowSend(ctx, "+447700900001", "192.0.2.1"))
+	assert.NoError(t, guard.AllowSend(ctx, "+447700900002", "192.0.2.2"))
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+447700900003", "192.0.2.3"), utils.ErrRateLimitExceeded)
+
+	// The client IP has its own
+	assert.NoError(t, guard.AllowSend(ctx, "+447700901001", "192.0.2.1"))
+	assert.NoError(t, guard.AllowSend(ctx, "+447700902001", "192.0.2.1"))
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+447700903001", "192.0.2.1"), utils.ErrRateLimitExceeded)
+
+	// Requests without a client IP skip the per-IP budget
+	assert.NoError(t, guard.AllowSend(ctx, "+447700904001", ""))
+}
+
+func TestFraudGuardRefusedSendsAreNotCounted(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard := newFraudGuard(t, config.FraudConfig{
+		IPHourly:             3,
+		RangeHourly:          1,
+		BreakerMinSends:      2,
+		BreakerMinConversion: 0.5,
+		BreakerWindow:        time.Hour,
+		BreakerCooldown:      time.Hour,
+	})
+	guard.now = func() time.Time { return now }
+
+	// The range budget refuses these after the IP budget took them; the IP
+	// budget gets them back and the breaker never sees them
+	assert.NoError(t, guard.AllowSend(ctx, "+447700900001", "192.0.2.1"))
+	for i := 0; i < 5; i++ {
+		assert.ErrorIs(t, guard.AllowSend(ctx, "+447700900002", "192.0.2.1"), utils.ErrRateLimitExceeded)
+	}
+	assert.NoError(t, guard.AllowSend(ctx, "+447700901001", "192.0.2.1"))
+	assert.NoError(t, guard.AllowSend(ctx, "+447700902001", "192.0.2.1"))
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+447700903001", "192.0.2.1"), utils.ErrRateLimitExceeded)
+
+	// Three sends to +44, none verified: the breaker opens once the window
+	// is over, and sends it refuses spend no budget
+	now = now.Add(time.Hour)
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+447700904001", "192.0.2.2"), utils.ErrDestinationSuspended)
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+447700904001", "192.0.2.2"), utils.ErrDestinationSuspended)
+
+	now = now.Add(time.Hour)
+	assert.NoError(t, guard.AllowSend(ctx, "+447700904001", "192.0.2.2"), "its range budget is untouched")
+}
+
+func TestFraudGuardBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard := newFraudGuard(t, config.FraudConfig{
+		BreakerMinSends:      4,
+		BreakerMinConversion: 0.5,
+		BreakerWindow:        time.Hour,
+		BreakerCooldown:      30 * time.Minute,
+	})
+	guard.now = func() time.Time { return now }
+
+	// Half of the codes to +44 are verified, one in four of those to +882.
+	// Windows are judged once they are over.
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, guard.AllowSend(ctx, "+447700900123", ""))
+		assert.NoError(t, guard.AllowSend(ctx, "+882123456789", ""))
+	}
+	guard.RecordVerified(ctx, "+447700900123")
+	guard.RecordVerified(ctx, "+882123456789")
+	now = now.Add(time.Hour)
+	guard.RecordVerified(ctx, "+447700900123")
+
+	assert.NoError(t, guard.AllowSend(ctx, "+447700900123", ""))
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+882123456789", ""), utils.ErrDestinationSuspended)
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+882987654321", ""), utils.ErrDestinationSuspended)
+	assert.NoError(t, guard.AllowSend(ctx, "+15551234567", ""), "other calling codes are unaffected")
+
+	// Verifications while suspended change nothing
+	guard.RecordVerified(ctx, "+882123456789")
+	assert.ErrorIs(t, guard.AllowSend(ctx, "+882123456789", ""), utils.ErrDestinationSuspended)
+
+	now = now.Add(30 * time.Minute)
+	assert.NoError(t, guard.AllowSend(ctx, "+882123456789", ""), "closes after the cooldown")
+}
+
+func TestFraudGuardDisabled(t *testing.T) {
+	guard := NewFraudGuard(config.FraudConfig{DeniedPrefixes: []string{"+1"}, GlobalHourly: 1}, ratelimit.NewMemoryStore())
+
+	assert.NoError(t, guard.AllowSend(context.Background(), "+15551234567", ""))
+	assert.NoError(t, guard.AllowSend(context.Background(), "+15551234567", ""))
+}
+
+func TestOTPServiceRefusesDeniedDestinations(t *testing.T) {
+	service, sender := newMemoryOTPService(t)
+	service.fraud = newFraudGuard(t, config.FraudConfig{DeniedPrefixes: []string{"+882"}})
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		err := service.SendOTP(ctx, "+882123456789", "192.0.2.1")
+		assert.ErrorIs(t, err, utils.ErrDestinationNotAllowed, "refused sends do not use up the number's limit")
+	}
+	assert.Empty(t, sender.codes)
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+	"go-auth/internal/scheduler"
+)
+
+// MaintenanceJobs returns the cleanup jobs that keep the OTP, session,
+// revocation and rate-limit tables from growing without bound. rateLimits is
+// nil when limiter state is not kept in the database.
+func MaintenanceJobs(cfg *config.SchedulerConfig, otpService *OTPService, tokenService *TokenService, rateLimits interfaces.RateLimitRepository) []scheduler.Job {
+	jobs := []scheduler.Job{
+		{
+			Name:     "otp_cleanup",
+			Interval: cfg.OTPInterval,
+			Timeout:  cfg.JobTimeout,
+			Run: func(ctx context.Context) (int64, error) {
+				return otpService.CleanupExpiredOTPs(ctx, cfg.OTPRetention)
+			},
+		},
+		{
+			Name:     "otp_attempt_cleanup",
+			Interval: cfg.OTPAttemptInterval,
+			Timeout:  cfg.JobTimeout,
+			Run: func(ctx context.Context) (int64, error) {
+				return otpService.CleanupOldAttempts(ctx, cfg.OTPAttemptRetention)
+			},
+		},
+		{
+			Name:     "session_cleanup",
+			Interval: cfg.SessionInterval,
+			Timeout:  cfg.JobTimeout,
+			Run: func(ctx context.Context) (int64, error) {
+				return tokenService.CleanupSessions(ctx, cfg.SessionRetention)
+			},
+		},
+		{
+			Name:     "revoked_token_cleanup",
+			Interval: cfg.RevokedTokenInterval,
+			Timeout:  cfg.JobTimeout,
+			Run: func(ctx context.Context) (int64, error) {
+				return tokenService.CleanupRevokedTokens(ctx)
+			},
+		},
+	}
+
+	if rateLimits != nil {
+		jobs = append(jobs, scheduler.Job{
+			Name:     "rate_limit_cleanup",
+			Interval: cfg.RateLimitInterval,
+			Timeout:  cfg.JobTimeout,
+			Run: func(ctx context.Context) (int64, error) {
+				return rateLimits.DeleteExpired(ctx, time.Now())
+			},
+		})
+	}
+
+	return jobs
+}
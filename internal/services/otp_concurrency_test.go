@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/database"
+	"go-auth/internal/models"
+	"go-auth/internal/repository"
+	"go-auth/pkg/utils"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// These tests need a disposable Postgres database, e.g.
+// TEST_DATABASE_DSN="host=localhost user=postgres password=password dbname=go_auth_test sslmode=disable"
+
+type capturingSender struct {
+	mu    sync.Mutex
+	codes map[string][]string
+}
+
+func (s *capturingSender) Send(ctx context.Context, phoneNumber, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[phoneNumber] = append(s.codes[phoneNumber], code)
+	return nil
+}
+
+type verifyFixture struct {
+	db           *gorm.DB
+	otpService   *OTPService
+	tokenService *TokenService
+	sender       *capturingSender
+}
+
+func newVerifyFixture(t *testing.T) *verifyFixture {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" || testing.Short() {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+
+	if utils.Logger == nil {
+		utils.InitLogger()
+		utils.Logger.SetLevel(logrus.PanicLevel)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	migrations, err := database.EmbeddedMigrations(database.DriverPostgres)
+	require.NoError(t, err)
+	migrator, err := database.NewMigrator(sqlDB, database.DriverPostgres, migrations)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		JWT: config.JWTConfig{
+			AccessTokenTTL:      time.Minute,
+			RefreshTokenTTL:     time.Hour,
+			RevocationCacheTTL:  time.Second,
+			RevocationCacheSize: 100,
+		},
+		OTP: config.OTPConfig{
+			ExpiryTime:        time.Minute,
+			MaxAttempts:       5,
+			RateWindow:        time.Minute,
+			Pepper:            "test-pepper",
+			MaxVerifyAttempts: 100,
+			LockoutBase:       time.Minute,
+			LockoutMax:        time.Hour,
+		},
+		SMS: config.SMSConfig{Provider: "test"},
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	sender := &capturingSender{codes: map[string][]string{}}
+
+	revocations := NewRevocationStore(cfg, repository.NewRevokedTokenRepository(db), userRepo, sessionRepo)
+	keyring := utils.NewKeyring(utils.NewHMACSigningKey("test", "test-secret"))
+
+	return &verifyFixture{
+		db: db,
+		otpService: NewOTPService(cfg, repository.NewOTPRepository(db), repository.NewOTPAttemptRepository(db),
+			repository.NewOTPLockoutRepository(db), userRepo, repository.NewTransactor(db, cfg.Database.TransactionTimeout),
+			repository.NewRateLimitRepository(db), sender),
+		tokenService: NewTokenService(cfg, keyring, sessionRepo, userRepo, revocations),
+		sender:       sender,
+	}
+}
+
+// uniquePhoneNumber keeps runs against the same database independent
+func uniquePhoneNumber() string {
+	return fmt.Sprintf("+1555%07d", uuid.New().ID()%10000000)
+}
+
+// login verifies a code and issues tokens the way the verify-otp handler does
+func (f *verifyFixture) login(phoneNumber, code string) (*models.User, *models.TokenPair, error) {
+	ctx := context.Background()
+	user, err := f.otpService.VerifyOTP(ctx, phoneNumber, code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := f.tokenService.IssueTokens(ctx, user, models.SessionMetadata{})
+	return user, tokens, err
+}
+
+func TestConcurrentVerifyIssuesOneToken(t *testing.T) {
+	f := newVerifyFixture(t)
+	phoneNumber := uniquePhoneNumber()
+
+	require.NoError(t, f.otpService.SendOTP(context.Background(), phoneNumber, ""))
+	code := f.sender.codes[phoneNumber][0]
+
+	const workers = 20
+	var wg sync.WaitGroup
+	results := make(chan error, workers)
+	issued := make(chan *models.TokenPair, workers)
+
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, tokens, err := f.login(phoneNumber, code)
+			results <- err
+			if err == nil {
+				issued <- tokens
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(results)
+	close(issued)
+
+	var failures int
+	for err := range results {
+		if err != nil {
+			assert.Equal(t, utils.ErrInvalidOTP, err)
+			failures++
+		}
+	}
+
+	assert.Len(t, issued, 1, "exactly one request may redeem the code")
+	assert.Equal(t, workers-1, failures)
+
+	var sessions int64
+	require.NoError(t, f.db.Model(&models.Session{}).
+		Where("user_id IN (?)", f.db.Model(&models.User{}).Select("id").Where("phone_number = ?", phoneNumber)).
+		Count(&sessions).Error)
+	assert.EqualValues(t, 1, sessions)
+}
+
+func TestConcurrentFirstLoginsShareOneUser(t *testing.T) {
+	f := newVerifyFixture(t)
+	phoneNumber := uniquePhoneNumber()
+
+	const logins = 4
+	for i := 0; i < logins; i++ {
+		require.NoError(t, f.otpService.SendOTP(context.Background(), phoneNumber, ""))
+	}
+	codes := f.sender.codes[phoneNumber]
+	require.Len(t, codes, logins)
+
+	var wg sync.WaitGroup
+	userIDs := make(chan uuid.UUID, logins)
+
+	start := make(chan struct{})
+	for _, code := range codes {
+		wg.Add(1)
+		go func(code string) {
+			defer wg.Done()
+			<-start
+			user, _, err := f.login(phoneNumber, code)
+			if assert.NoError(t, err) {
+				userIDs <- user.ID
+			}
+		}(code)
+	}
+	close(start)
+	wg.Wait()
+	close(userIDs)
+
+	var first uuid.UUID
+	for id := range userIDs {
+		if first == uuid.Nil {
+			first = id
+		}
+		assert.Equal(t, first, id, "every login must resolve to the same user")
+	}
+
+	var users int64
+	require.NoError(t, f.db.Model(&models.User{}).Where("phone_number = ?", phoneNumber).Count(&users).Error)
+	assert.EqualValues(t, 1, users)
+}
+
+func TestVerifyRejectsReusedCode(t *testing.T) {
+	f := newVerifyFixture(t)
+	phoneNumber := uniquePhoneNumber()
+
+	require.NoError(t, f.otpService.SendOTP(context.Background(), phoneNumber, ""))
+	code := f.sender.codes[phoneNumber][0]
+
+	_, _, err := f.login(phoneNumber, code)
+	require.NoError(t, err)
+
+	_, _, err = f.login(phoneNumber, code)
+	assert.Equal(t, utils.ErrInvalidOTP, err)
+}
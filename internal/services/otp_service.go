@@ -1,38 +1,92 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"go-auth/internal/config"
 	"go-auth/internal/interfaces"
+	"go-auth/internal/metrics"
 	"go-auth/internal/models"
+	"go-auth/internal/tracing"
+	"go-auth/pkg/ratelimit"
 	"go-auth/pkg/utils"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type OTPService struct {
 	config         *config.Config
 	otpRepo        interfaces.OTPRepository
 	otpAttemptRepo interfaces.OTPAttemptRepository
+	lockoutRepo    interfaces.OTPLockoutRepository
 	userRepo       interfaces.UserRepository
+	transactor     interfaces.Transactor
+	sender         interfaces.OTPSender
+	phoneLimiter   *ratelimit.SlidingWindow
+	fraud          *FraudGuard
 }
 
-func NewOTPService(config *config.Config, otpRepo interfaces.OTPRepository, otpAttemptRepo interfaces.OTPAttemptRepository, userRepo interfaces.UserRepository) *OTPService {
+// lockoutLevelTTL is how long a phone number keeps its backoff level after
+// the last lockout before starting again from the base duration
+const lockoutLevelTTL = 24 * time.Hour
+
+// NewOTPService limits OTP requests per phone number with a sliding window
+// kept in rateLimits, so the check and the count are one atomic step. The
+// send budgets and breakers of the fraud guard are kept there too.
+func NewOTPService(config *config.Config, otpRepo interfaces.OTPRepository, otpAttemptRepo interfaces.OTPAttemptRepository, lockoutRepo interfaces.OTPLockoutRepository, userRepo interfaces.UserRepository, transactor interfaces.Transactor, rateLimits ratelimit.Store, sender interfaces.OTPSender) *OTPService {
 	return &OTPService{
 		config:         config,
 		otpRepo:        otpRepo,
 		otpAttemptRepo: otpAttemptRepo,
+		lockoutRepo:    lockoutRepo,
 		userRepo:       userRepo,
+		transactor:     transactor,
+		sender:         sender,
+		phoneLimiter:   ratelimit.NewSlidingWindow(rateLimits, "otp:phone", config.OTP.MaxAttempts, config.OTP.RateWindow),
+		fraud:          NewFraudGuard(config.Fraud, rateLimits),
 	}
 }
 
-func (s *OTPService) SendOTP(phoneNumber string) error {
+// SendOTP sends a code to phoneNumber. clientIP is the address the request
+// came from, for the fraud guard's per-IP budget. The number is normalized
+// first, so it is limited and stored the same with or without its +.
+func (s *OTPService) SendOTP(ctx context.Context, phoneNumber, clientIP string) (err error) {
+	ctx, span := tracing.Start(ctx, "OTPService.SendOTP")
+	defer func() { tracing.End(span, err) }()
+
+	outcome := metrics.SendError
+	defer func() { metrics.ObserveOTPSend(outcome) }()
+
 	if validationErrors := utils.ValidatePhoneNumber(phoneNumber); validationErrors.HasErrors() {
-		utils.LogSecurityEvent("invalid_phone_number", "", phoneNumber, validationErrors.Error())
+		outcome = metrics.SendInvalidPhone
+		utils.LogSecurityEvent(ctx, "invalid_phone_number", "", phoneNumber, validationErrors.Error())
 		return fmt.Errorf("validation failed: %s", validationErrors.Error())
 	}
+	phoneNumber = utils.NormalizePhoneNumber(phoneNumber)
 
-	if err := s.checkRateLimit(phoneNumber); err != nil {
+	if err := s.checkRateLimit(ctx, phoneNumber); err != nil {
+		if err == utils.ErrRateLimitExceeded {
+			outcome = metrics.SendRateLimited
+		}
+		return err
+	}
+
+	if err := s.fraud.AllowSend(ctx, phoneNumber, clientIP); err != nil {
+		// A send the fraud guard refuses does not use up the number's limit
+		if refundErr := s.phoneLimiter.Refund(ctx, phoneNumber); refundErr != nil {
+			utils.LogWithContext(ctx).WithError(refundErr).Warn("Failed to refund the OTP rate limit")
+		}
+
+		switch err {
+		case utils.ErrDestinationNotAllowed:
+			outcome = metrics.SendBlocked
+		case utils.ErrDestinationSuspended:
+			outcome = metrics.SendSuspended
+		case utils.ErrRateLimitExceeded:
+			outcome = metrics.SendRateLimited
+		}
 		return err
 	}
 
@@ -44,79 +98,255 @@ func (s *OTPService) SendOTP(phoneNumber string) error {
 	expiresAt := time.Now().Add(s.config.OTP.ExpiryTime)
 	otp := &models.OTP{
 		PhoneNumber: phoneNumber,
-		Code:        otpCode,
+		CodeHash:    utils.HashOTP(otpCode, s.config.OTP.Pepper),
 		ExpiresAt:   expiresAt,
 		IsUsed:      false,
 	}
 
-	if err := s.otpRepo.Create(otp); err != nil {
+	if err := s.otpRepo.Create(ctx, otp); err != nil {
 		return err
 	}
 
+	// The attempts are an audit trail of requests; the limit is enforced by
+	// phoneLimiter, so a failed write does not stop the code going out
 	attempt := &models.OTPAttempt{
 		PhoneNumber: phoneNumber,
 	}
-	s.otpAttemptRepo.Create(attempt)
+	if err := s.otpAttemptRepo.Create(ctx, attempt); err != nil {
+		utils.LogWithContext(ctx).WithError(err).Warn("Failed to record OTP request")
+	}
+
+	utils.LogOTPGenerated(ctx, phoneNumber, otpCode, expiresAt)
+
+	err = s.deliver(ctx, phoneNumber, otpCode)
+
+	if err != nil {
+		outcome = metrics.SendDeliveryFailed
+		utils.LogOTPDelivery(ctx, phoneNumber, s.config.SMS.Provider, false, err.Error())
+		return utils.ErrOTPDeliveryFailed
+	}
 
-	utils.LogOTPGenerated(phoneNumber, otpCode, expiresAt)
+	outcome = metrics.SendSent
+	utils.LogOTPDelivery(ctx, phoneNumber, s.config.SMS.Provider, true, "")
 	return nil
 }
 
-func (s *OTPService) VerifyOTP(phoneNumber, code string) (*models.User, error) {
+// VerifyOTP checks code against the pending codes of phoneNumber and signs
+// the user in, registering them on their first sign-in
+func (s *OTPService) VerifyOTP(ctx context.Context, phoneNumber, code string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "OTPService.VerifyOTP")
+	defer func() { tracing.End(span, err) }()
+
+	outcome := metrics.VerifyError
+	defer func() { metrics.ObserveOTPVerification(outcome) }()
+
 	if validationErrors := utils.ValidatePhoneNumber(phoneNumber); validationErrors.HasErrors() {
+		outcome = metrics.VerifyInvalidInput
 		return nil, fmt.Errorf("validation failed: %s", validationErrors.Error())
 	}
+	phoneNumber = utils.NormalizePhoneNumber(phoneNumber)
 
 	if validationErrors := utils.ValidateOTPCode(code); validationErrors.HasErrors() {
+		outcome = metrics.VerifyInvalidInput
 		return nil, fmt.Errorf("validation failed: %s", validationErrors.Error())
 	}
 
-	otp, err := s.otpRepo.GetValidOTP(phoneNumber, code)
+	lockout, err := s.lockoutRepo.Get(ctx, phoneNumber)
 	if err != nil {
-		utils.LogOTPVerification(phoneNumber, code, false, err.Error())
 		return nil, err
 	}
 
-	if err := s.otpRepo.MarkAsUsed(otp.ID); err != nil {
+	if lockout.IsLocked() {
+		outcome = metrics.VerifyLocked
+		utils.LogSecurityEvent(ctx, "otp_verification_locked", "", phoneNumber, "verification attempted during lockout")
+		return nil, utils.NewOTPLockedError(time.Until(lockout.LockedUntil))
+	}
+
+	otps, err := s.otpRepo.GetPendingOTPs(ctx, phoneNumber, s.config.OTP.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	otp := s.matchOTP(otps, code)
+	if otp == nil {
+		utils.LogOTPVerification(ctx, phoneNumber, false, utils.ErrInvalidOTP.Message)
+		err := s.recordFailedAttempt(ctx, lockout)
+		if appErr, ok := utils.IsAppError(err); ok {
+			outcome = metrics.VerifyWrongCode
+			if appErr.RetryAfter > 0 {
+				outcome = metrics.VerifyLockedOut
+			}
+		}
 		return nil, err
 	}
 
-	utils.LogOTPVerification(phoneNumber, code, true, "OTP verified successfully")
+	if otp.IsExpired() {
+		outcome = metrics.VerifyExpired
+		utils.LogOTPVerification(ctx, phoneNumber, false, utils.ErrOTPExpired.Message)
+		return nil, utils.ErrOTPExpired
+	}
 
-	user, err := s.userRepo.GetByPhoneNumber(phoneNumber)
+	user, created, err := s.consumeOTP(ctx, otp, lockout)
 	if err != nil {
-		if err == utils.ErrUserNotFound {
-			newUser := &models.User{
-				PhoneNumber: phoneNumber,
-			}
-			if err := s.userRepo.Create(newUser); err != nil {
-				return nil, err
-			}
-			utils.LogUserRegistration(newUser.ID.String(), phoneNumber)
-			return newUser, nil
+		if err == utils.ErrInvalidOTP {
+			outcome = metrics.VerifyAlreadyUsed
+			utils.LogOTPVerification(ctx, phoneNumber, false, "OTP already used")
 		}
 		return nil, err
 	}
 
-	utils.LogUserLogin(user.ID.String(), phoneNumber)
+	outcome = metrics.VerifySuccess
+	utils.LogOTPVerification(ctx, phoneNumber, true, "OTP verified successfully")
+	s.fraud.RecordVerified(ctx, phoneNumber)
+
+	if created {
+		utils.LogUserRegistration(ctx, user.ID.String(), phoneNumber)
+	} else {
+		utils.LogUserLogin(ctx, user.ID.String(), phoneNumber)
+	}
+
 	return user, nil
 }
 
-func (s *OTPService) checkRateLimit(phoneNumber string) error {
-	cutoffTime := time.Now().Add(-s.config.OTP.RateWindow)
-	count, err := s.otpAttemptRepo.CountRecentAttempts(phoneNumber, cutoffTime)
+// deliver hands the code to the SMS provider in its own span
+func (s *OTPService) deliver(ctx context.Context, phoneNumber, code string) (err error) {
+	ctx, span := tracing.Start(ctx, "sms.Send", attribute.String("sms.provider", s.config.SMS.Provider))
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+	err = s.sender.Send(ctx, phoneNumber, code)
+	metrics.ObserveOTPDelivery(s.config.SMS.Provider, err == nil, time.Since(start))
+	return err
+}
+
+// consumeOTP marks the code as used, clears the lockout and provisions the
+// user in one transaction. Only one of several concurrent requests presenting
+// the same code can consume it; the others get ErrInvalidOTP.
+func (s *OTPService) consumeOTP(ctx context.Context, otp *models.OTP, lockout *models.OTPLockout) (*models.User, bool, error) {
+	var user *models.User
+	var created bool
+
+	err := s.transactor.WithinTransaction(ctx, func(repos interfaces.TxRepositories) error {
+		consumed, err := repos.OTPs.Consume(ctx, otp.ID)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return utils.ErrInvalidOTP
+		}
+
+		if lockout.Level > 0 {
+			if err := repos.OTPLockouts.Delete(ctx, otp.PhoneNumber); err != nil {
+				return err
+			}
+		}
+
+		user, created, err = repos.Users.FindOrCreateByPhoneNumber(ctx, otp.PhoneNumber)
+		return err
+	})
+
+	return user, created, err
+}
+
+// recordFailedAttempt counts a wrong guess and, once a code has been
+// invalidated by too many of them, locks the phone number with exponential
+// backoff
+func (s *OTPService) recordFailedAttempt(ctx context.Context, lockout *models.OTPLockout) error {
+	invalidated, err := s.otpRepo.RecordFailedAttempt(ctx, lockout.PhoneNumber, s.config.OTP.MaxVerifyAttempts)
 	if err != nil {
 		return err
 	}
 
-	if count >= int64(s.config.OTP.MaxAttempts) {
-		utils.LogRateLimit(phoneNumber, int(count), s.config.OTP.MaxAttempts)
+	if !invalidated {
+		return utils.ErrInvalidOTP
+	}
+
+	if time.Since(lockout.UpdatedAt) > lockoutLevelTTL {
+		lockout.Level = 0
+	}
+
+	lockout.Level++
+	wait := lockoutDuration(lockout.Level, s.config.OTP.LockoutBase, s.config.OTP.LockoutMax)
+	lockout.LockedUntil = time.Now().Add(wait)
+
+	if err := s.lockoutRepo.Save(ctx, lockout); err != nil {
+		return err
+	}
+
+	utils.LogSecurityEvent(ctx, "otp_verification_lockout", "", lockout.PhoneNumber,
+		fmt.Sprintf("code invalidated after %d failed attempts, locked for %s (level %d)",
+			s.config.OTP.MaxVerifyAttempts, wait, lockout.Level))
+
+	return utils.NewOTPLockedError(wait)
+}
+
+// lockoutDuration doubles the base duration for every level above the first,
+// capped at max
+func lockoutDuration(level int, base, max time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < level && wait < max; i++ {
+		wait *= 2
+	}
+
+	if wait > max {
+		wait = max
+	}
+
+	return wait
+}
+
+// matchOTP returns the pending OTP whose hash matches code. Every candidate is
+// compared so the time taken does not depend on which one matched.
+func (s *OTPService) matchOTP(otps []models.OTP, code string) *models.OTP {
+	var matched *models.OTP
+	for i := range otps {
+		if utils.VerifyOTPHash(code, otps[i].CodeHash, s.config.OTP.Pepper) && matched == nil {
+			matched = &otps[i]
+		}
+	}
+	return matched
+}
+
+// checkRateLimit counts an OTP request against the phone number's limit.
+// Concurrent requests cannot all slip under it, as the count is taken and
+// incremented in one step.
+func (s *OTPService) checkRateLimit(ctx context.Context, phoneNumber string) error {
+	result, err := s.phoneLimiter.Allow(ctx, phoneNumber)
+	if err != nil {
+		return err
+	}
+
+	if !result.Allowed {
+		metrics.ObserveOTPRateLimited()
+		utils.LogRateLimit(ctx, phoneNumber, result.Limit-result.Remaining, result.Limit)
 		return utils.ErrRateLimitExceeded
 	}
 
 	return nil
 }
 
-func (s *OTPService) CleanupExpiredOTPs() error {
-	return s.otpRepo.DeleteExpired()
+// CleanupExpiredOTPs deletes codes that expired more than retention ago,
+// together with lockouts whose backoff level has lapsed
+func (s *OTPService) CleanupExpiredOTPs(ctx context.Context, retention time.Duration) (int64, error) {
+	deleted, err := s.otpRepo.DeleteExpired(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	lockouts, err := s.lockoutRepo.DeleteStale(ctx, time.Now().Add(-lockoutLevelTTL))
+	if err != nil {
+		return deleted, err
+	}
+
+	return deleted + lockouts, nil
+}
+
+// CleanupOldAttempts deletes OTP request records older than retention. The
+// retention must cover the rate limit window.
+func (s *OTPService) CleanupOldAttempts(ctx context.Context, retention time.Duration) (int64, error) {
+	if retention < s.config.OTP.RateWindow {
+		retention = s.config.OTP.RateWindow
+	}
+
+	return s.otpAttemptRepo.DeleteOldAttempts(ctx, time.Now().Add(-retention))
 }
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/repository/memory"
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	base := time.Minute
+	max := 10 * time.Minute
+
+	tests := []struct {
+		level    int
+		expected time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, lockoutDuration(tt.level, base, max), "level %d", tt.level)
+	}
+}
+
+// newMemoryOTPService runs the OTP flow on in-memory repositories
+func newMemoryOTPService(t *testing.T) (*OTPService, *capturingSender) {
+	t.Helper()
+
+	if utils.Logger == nil {
+		utils.InitLogger()
+		utils.Logger.SetLevel(logrus.PanicLevel)
+	}
+
+	cfg := &config.Config{
+		OTP: config.OTPConfig{
+			ExpiryTime:        time.Minute,
+			MaxAttempts:       2,
+			RateWindow:        time.Minute,
+			Pepper:            "test-pepper",
+			MaxVerifyAttempts: 3,
+			LockoutBase:       time.Minute,
+			LockoutMax:        time.Hour,
+		},
+		SMS: config.SMSConfig{Provider: "test"},
+	}
+
+	store := memory.NewStore()
+	sender := &capturingSender{codes: map[string][]string{}}
+	service := NewOTPService(cfg, memory.NewOTPRepository(store), memory.NewOTPAttemptRepository(store),
+		memory.NewOTPLockoutRepository(store), memory.NewUserRepository(store), memory.NewTransactor(store),
+		ratelimit.NewMemoryStore(), sender)
+
+	return service, sender
+}
+
+func TestOTPServiceSendAndVerify(t *testing.T) {
+	service, sender := newMemoryOTPService(t)
+	ctx := context.Background()
+	phoneNumber := "+15551234567"
+
+	require.NoError(t, service.SendOTP(ctx, phoneNumber, ""))
+	code := sender.codes[phoneNumber][0]
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	_, err := service.VerifyOTP(ctx, phoneNumber, wrong)
+	assert.ErrorIs(t, err, utils.ErrInvalidOTP)
+
+	user, err := service.VerifyOTP(ctx, phoneNumber, code)
+	require.NoError(t, err)
+	assert.Equal(t, phoneNumber, user.PhoneNumber)
+
+	_, err = service.VerifyOTP(ctx, phoneNumber, code)
+	assert.Error(t, err, "a code works once")
+
+	// The next login finds the same user, with or without the +
+	require.NoError(t, service.SendOTP(ctx, "15551234567", ""))
+	again, err := service.VerifyOTP(ctx, "15551234567", sender.codes[phoneNumber][1])
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, again.ID)
+}
+
+func TestOTPServiceRateLimit(t *testing.T) {
+	service, _ := newMemoryOTPService(t)
+	ctx := context.Background()
+	phoneNumber := "+15551234567"
+
+	require.NoError(t, service.SendOTP(ctx, phoneNumber, ""))
+	require.NoError(t, service.SendOTP(ctx, phoneNumber, ""))
+	assert.ErrorIs(t, service.SendOTP(ctx, phoneNumber, ""), utils.ErrRateLimitExceeded)
+	assert.ErrorIs(t, service.SendOTP(ctx, "15551234567", ""), utils.ErrRateLimitExceeded, "the + makes no difference")
+}
+
+func TestOTPServiceRateLimitHoldsUnderBursts(t *testing.T) {
+	service, sender := newMemoryOTPService(t)
+	phoneNumber := "+15551234567"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.SendOTP(context.Background(), phoneNumber, "")
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, sender.codes[phoneNumber], 2, "OTP_MAX_ATTEMPTS holds for concurrent requests")
+}
+
+func TestOTPServiceLocksOutAfterRepeatedGuesses(t *testing.T) {
+	service, sender := newMemoryOTPService(t)
+	ctx := context.Background()
+	phoneNumber := "+15551234567"
+
+	require.NoError(t, service.SendOTP(ctx, phoneNumber, ""))
+	code := sender.codes[phoneNumber][0]
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	for i := 0; i < 3; i++ {
+		_, err := service.VerifyOTP(ctx, phoneNumber, wrong)
+		require.Error(t, err)
+	}
+
+	_, err := service.VerifyOTP(ctx, phoneNumber, code)
+	appErr, ok := utils.IsAppError(err)
+	require.True(t, ok, "got %v", err)
+	assert.Positive(t, appErr.RetryAfter, "the phone number is locked")
+}
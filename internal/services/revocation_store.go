@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+	"go-auth/pkg/cache"
+	"go-auth/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+// RevocationStore decides whether an otherwise valid access token has been
+// revoked: individually by jti, through its session, or for the whole user by
+// token version. Lookups are cached in memory so the auth hot path rarely
+// reaches Postgres; revocations made on this replica take effect immediately,
+// those made by other replicas once the cached entry expires.
+type RevocationStore struct {
+	revokedRepo    interfaces.RevokedTokenRepository
+	userRepo       interfaces.UserRepository
+	sessionRepo    interfaces.SessionRepository
+	revokedJTIs    *cache.LRU[string, bool]
+	userVersions   *cache.LRU[uuid.UUID, int]
+	activeSessions *cache.LRU[uuid.UUID, bool]
+}
+
+func NewRevocationStore(config *config.Config, revokedRepo interfaces.RevokedTokenRepository, userRepo interfaces.UserRepository, sessionRepo interfaces.SessionRepository) *RevocationStore {
+	return &RevocationStore{
+		revokedRepo:    revokedRepo,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		revokedJTIs:    cache.NewLRU[string, bool](config.JWT.RevocationCacheSize, config.JWT.RevocationCacheTTL),
+		userVersions:   cache.NewLRU[uuid.UUID, int](config.JWT.RevocationCacheSize, config.JWT.RevocationCacheTTL),
+		activeSessions: cache.NewLRU[uuid.UUID, bool](config.JWT.RevocationCacheSize, config.JWT.RevocationCacheTTL),
+	}
+}
+
+func (s *RevocationStore) IsRevoked(ctx context.Context, claims *utils.JWTClaims) (bool, error) {
+	revoked, ok := s.revokedJTIs.Get(claims.ID)
+	if !ok {
+		var err error
+		revoked, err = s.revokedRepo.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return false, err
+		}
+		s.revokedJTIs.Set(claims.ID, revoked)
+	}
+
+	if revoked {
+		return true, nil
+	}
+
+	if claims.SessionID != uuid.Nil {
+		active, err := s.isSessionActive(ctx, claims.SessionID)
+		if err != nil {
+			return false, err
+		}
+		if !active {
+			return true, nil
+		}
+	}
+
+	version, ok := s.userVersions.Get(claims.UserID)
+	if !ok {
+		user, err := s.userRepo.GetByID(ctx, claims.UserID)
+		if err != nil {
+			if err == utils.ErrUserNotFound {
+				return true, nil
+			}
+			return false, err
+		}
+		version = user.TokenVersion
+		s.userVersions.Set(claims.UserID, version)
+	}
+
+	return claims.TokenVersion < version, nil
+}
+
+// isSessionActive checks the session behind a token. Cache misses also
+// refresh the session's last-seen time, which keeps that write to at most
+// once per cache TTL.
+func (s *RevocationStore) isSessionActive(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	if active, ok := s.activeSessions.Get(sessionID); ok {
+		return active, nil
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if err == utils.ErrSessionNotFound {
+			s.activeSessions.Set(sessionID, false)
+			return false, nil
+		}
+		return false, err
+	}
+
+	active := session.IsActive()
+	s.activeSessions.Set(sessionID, active)
+
+	if active {
+		if err := s.sessionRepo.Touch(ctx, sessionID, time.Now()); err != nil {
+			utils.LogWithContext(ctx).WithError(err).WithField("session_id", sessionID.String()).
+				Error("Failed to update session last seen")
+		}
+	}
+
+	return active, nil
+}
+
+// RevokeSession revokes a session and every access token issued for it
+func (s *RevocationStore) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	if err := s.sessionRepo.Revoke(ctx, sessionID); err != nil {
+		return err
+	}
+
+	s.activeSessions.Set(sessionID, false)
+	return nil
+}
+
+// RevokeOtherSessions revokes every session of the user except keepID
+func (s *RevocationStore) RevokeOtherSessions(ctx context.Context, userID, keepID uuid.UUID) error {
+	sessions, err := s.sessionRepo.ListActiveForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeAllForUserExcept(ctx, userID, keepID); err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID != keepID {
+			s.activeSessions.Set(session.ID, false)
+		}
+	}
+
+	return nil
+}
+
+// RevokeToken blacklists a single access token until it expires
+func (s *RevocationStore) RevokeToken(ctx context.Context, claims *utils.JWTClaims) error {
+	expiresAt := time.Now()
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	err := s.revokedRepo.Create(ctx, &models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.revokedJTIs.Set(claims.ID, true)
+	return nil
+}
+
+// DeleteExpired forgets revoked tokens that have expired anyway
+func (s *RevocationStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.revokedRepo.DeleteExpired(ctx, time.Now())
+}
+
+// RevokeUserTokens invalidates every access token issued to the user so far
+func (s *RevocationStore) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	if err := s.userRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+
+	s.userVersions.Delete(userID)
+	return nil
+}
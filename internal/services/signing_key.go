@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/pkg/utils"
+)
+
+// defaultHMACKeyID identifies the shared-secret key in the kid header
+const defaultHMACKeyID = "default"
+
+// NewKeyring loads the JWT keys selected by the configuration. With
+// JWT_KEYS_DIR set the keyring manifest in that directory is used, otherwise
+// the keyring holds the single key from JWT_SECRET or JWT_PRIVATE_KEY_FILE.
+func NewKeyring(cfg *config.JWTConfig) (*utils.Keyring, error) {
+	if cfg.KeysDir != "" {
+		return utils.LoadKeyring(cfg.KeysDir)
+	}
+
+	key, err := newSigningKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.NewKeyring(key), nil
+}
+
+func newSigningKey(cfg *config.JWTConfig) (*utils.SigningKey, error) {
+	if cfg.Algorithm == "" || cfg.Algorithm == "HS256" {
+		kid := cfg.KeyID
+		if kid == "" {
+			kid = defaultHMACKeyID
+		}
+		return utils.NewHMACSigningKey(kid, cfg.Secret), nil
+	}
+
+	if cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for %s", cfg.Algorithm)
+	}
+
+	return utils.LoadSigningKey(cfg.KeyID, cfg.Algorithm, cfg.PrivateKeyFile)
+}
+
+// StartKeyringReloader re-reads the keyring directory periodically so keys
+// generated, promoted or retired with keyctl take effect without a restart.
+// A manifest that fails to load keeps the current keys in place.
+func StartKeyringReloader(keyring *utils.Keyring, cfg *config.JWTConfig) (stop func()) {
+	if cfg.KeysDir == "" || cfg.KeyringReload <= 0 {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(cfg.KeyringReload)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				reloaded, err := utils.LoadKeyring(cfg.KeysDir)
+				if err != nil {
+					utils.LogError(err, "Failed to reload JWT keyring", map[string]interface{}{
+						"keys_dir": cfg.KeysDir,
+					})
+					continue
+				}
+
+				previous := keyring.Active().ID
+				keyring.Replace(reloaded)
+				if active := keyring.Active().ID; active != previous {
+					utils.LogSecurityEvent(context.Background(), "signing_key_rotated", "", "", "active signing key is now "+active)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+	"go-auth/internal/models"
+	"go-auth/internal/tracing"
+	"go-auth/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+type TokenService struct {
+	config      *config.Config
+	keyring     *utils.Keyring
+	sessionRepo interfaces.SessionRepository
+	userRepo    interfaces.UserRepository
+	revocations *RevocationStore
+}
+
+func NewTokenService(config *config.Config, keyring *utils.Keyring, sessionRepo interfaces.SessionRepository, userRepo interfaces.UserRepository, revocations *RevocationStore) *TokenService {
+	return &TokenService{
+		config:      config,
+		keyring:     keyring,
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		revocations: revocations,
+	}
+}
+
+// IssueTokens starts a new session for the user and returns its first token pair
+func (s *TokenService) IssueTokens(ctx context.Context, user *models.User, metadata models.SessionMetadata) (_ *models.TokenPair, err error) {
+	ctx, span := tracing.Start(ctx, "TokenService.IssueTokens")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+	session := &models.Session{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		UserAgent:  metadata.UserAgent,
+		ClientIP:   metadata.ClientIP,
+		DeviceName: metadata.DeviceName,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.config.JWT.RefreshTokenTTL),
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	session.RefreshTokenHash = utils.HashRefreshToken(refreshToken)
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.tokenPair(user, session.ID, refreshToken)
+}
+
+// Refresh rotates a refresh token. Presenting the token the session was last
+// rotated away from means it leaked, so the whole session is revoked. Any
+// other wrong token is only refused: session IDs are not secret, so a
+// forged token must not be able to end someone else's session.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string, metadata models.SessionMetadata) (_ *models.TokenPair, err error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Refresh")
+	defer func() { tracing.End(span, err) }()
+
+	session, err := s.sessionForRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if !session.IsActive() {
+		return nil, utils.ErrInvalidRefreshToken
+	}
+
+	if !utils.RefreshTokenMatches(refreshToken, session.RefreshTokenHash) {
+		if session.PreviousRefreshTokenHash != "" && utils.RefreshTokenMatches(refreshToken, session.PreviousRefreshTokenHash) {
+			return nil, s.revokeReusedSession(ctx, session)
+		}
+		return nil, utils.ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := utils.GenerateRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.sessionRepo.Rotate(ctx, session.ID, session.RefreshTokenHash,
+		utils.HashRefreshToken(newRefreshToken), time.Now().Add(s.config.JWT.RefreshTokenTTL), metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another request rotated the same token first
+	if !rotated {
+		return nil, s.revokeReusedSession(ctx, session)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokenPair(user, session.ID, newRefreshToken)
+}
+
+// ValidateAccessToken checks the signature and expiry of an access token and
+// that it has not been revoked
+func (s *TokenService) ValidateAccessToken(ctx context.Context, token string) (_ *utils.JWTClaims, err error) {
+	ctx, span := tracing.Start(ctx, "TokenService.ValidateAccessToken")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := utils.ValidateJWT(token, s.keyring, s.config.JWT.Issuer)
+	if err != nil {
+		return nil, utils.ErrInvalidToken.WithDetails(err.Error())
+	}
+
+	revoked, err := s.revocations.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, utils.ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// Logout revokes the presented access token and, when given, the session
+// behind the refresh token
+func (s *TokenService) Logout(ctx context.Context, claims *utils.JWTClaims, refreshToken string) error {
+	if err := s.revocations.RevokeToken(ctx, claims); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	session, err := s.sessionForRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != claims.UserID || !utils.RefreshTokenMatches(refreshToken, session.RefreshTokenHash) {
+		return utils.ErrInvalidRefreshToken
+	}
+
+	return s.revocations.RevokeSession(ctx, session.ID)
+}
+
+// LogoutAll revokes every access token and session the user holds
+func (s *TokenService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if err := s.revocations.RevokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+
+	utils.LogSecurityEvent(ctx, "logout_all", userID.String(), "", "all sessions and access tokens revoked")
+	return nil
+}
+
+// JWKS returns the public keys that verify access tokens, including keys that
+// are published ahead of promotion or still within their retirement window.
+// It is empty when tokens are signed with a shared secret.
+func (s *TokenService) JWKS() utils.JWKSet {
+	return utils.JWKSet{Keys: s.keyring.PublicJWKs()}
+}
+
+// ListSessions returns the user's active sessions, flagging the current one
+func (s *TokenService) ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]models.SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActiveForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = models.SessionResponse{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			ClientIP:   session.ClientIP,
+			CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+			LastSeenAt: session.LastSeenAt.Format(time.RFC3339),
+			Current:    session.ID == currentSessionID,
+		}
+	}
+
+	return responses, nil
+}
+
+// RevokeSession revokes one of the user's sessions. Sessions of other users
+// are reported as not found.
+func (s *TokenService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userID {
+		return utils.ErrSessionNotFound
+	}
+
+	if err := s.revocations.RevokeSession(ctx, sessionID); err != nil {
+		return err
+	}
+
+	utils.LogSecurityEvent(ctx, "session_revoked", userID.String(), "", "session "+sessionID.String()+" revoked")
+	return nil
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current session
+func (s *TokenService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID uuid.UUID) error {
+	if err := s.revocations.RevokeOtherSessions(ctx, userID, currentSessionID); err != nil {
+		return err
+	}
+
+	utils.LogSecurityEvent(ctx, "sessions_revoked", userID.String(), "", "all other sessions revoked")
+	return nil
+}
+
+// CleanupSessions deletes sessions that expired or were revoked more than
+// retention ago
+func (s *TokenService) CleanupSessions(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.sessionRepo.DeleteInactive(ctx, time.Now().Add(-retention))
+}
+
+// CleanupRevokedTokens drops blacklist entries for tokens past their expiry
+func (s *TokenService) CleanupRevokedTokens(ctx context.Context) (int64, error) {
+	return s.revocations.DeleteExpired(ctx)
+}
+
+func (s *TokenService) sessionForRefreshToken(ctx context.Context, refreshToken string) (*models.Session, error) {
+	sessionID, err := utils.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, utils.ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if err == utils.ErrSessionNotFound {
+			return nil, utils.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *TokenService) revokeReusedSession(ctx context.Context, session *models.Session) error {
+	if err := s.revocations.RevokeSession(ctx, session.ID); err != nil {
+		return err
+	}
+
+	utils.LogSecurityEvent(ctx, "refresh_token_reuse", session.UserID.String(), "",
+		"refresh token reused, session "+session.ID.String()+" revoked")
+
+	return utils.ErrRefreshTokenReused
+}
+
+func (s *TokenService) tokenPair(user *models.User, sessionID uuid.UUID, refreshToken string) (*models.TokenPair, error) {
+	permissions := user.Role.Permissions()
+	subject := utils.TokenSubject{
+		UserID:       user.ID,
+		PhoneNumber:  user.PhoneNumber,
+		Role:         string(user.Role),
+		Permissions:  make([]string, len(permissions)),
+		TokenVersion: user.TokenVersion,
+		SessionID:    sessionID,
+	}
+	for i, permission := range permissions {
+		subject.Permissions[i] = string(permission)
+	}
+
+	accessToken, err := utils.GenerateJWT(subject, s.keyring.Active(), s.config.JWT.Issuer, s.config.JWT.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.config.JWT.AccessTokenTTL.Seconds()),
+	}, nil
+}
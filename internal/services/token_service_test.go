@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/database"
+	"go-auth/internal/models"
+	"go-auth/internal/repository"
+	"go-auth/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newSQLiteTokenService issues tokens for a new user, with sessions in a
+// migrated in-memory database
+func newSQLiteTokenService(t *testing.T) (*TokenService, *models.User) {
+	t.Helper()
+
+	if utils.Logger == nil {
+		utils.InitLogger()
+		utils.Logger.SetLevel(logrus.PanicLevel)
+	}
+
+	db, err := database.OpenSQLite(":memory:", &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrations, err := database.EmbeddedMigrations(database.DriverSQLite)
+	require.NoError(t, err)
+	migrator, err := database.NewMigrator(sqlDB, database.DriverSQLite, migrations)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		JWT: config.JWTConfig{
+			AccessTokenTTL:      time.Minute,
+			RefreshTokenTTL:     time.Hour,
+			RevocationCacheTTL:  time.Second,
+			RevocationCacheSize: 100,
+		},
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	revocations := NewRevocationStore(cfg, repository.NewRevokedTokenRepository(db), userRepo, sessionRepo)
+	keyring := utils.NewKeyring(utils.NewHMACSigningKey("test", "test-secret"))
+
+	user, _, err := userRepo.FindOrCreateByPhoneNumber(context.Background(), "+15551234567")
+	require.NoError(t, err)
+
+	return NewTokenService(cfg, keyring, sessionRepo, userRepo, revocations), user
+}
+
+func TestRefreshRevokesTheSessionOnReuse(t *testing.T) {
+	service, user := newSQLiteTokenService(t)
+	ctx := context.Background()
+
+	first, err := service.IssueTokens(ctx, user, models.SessionMetadata{})
+	require.NoError(t, err)
+	second, err := service.Refresh(ctx, first.RefreshToken, models.SessionMetadata{})
+	require.NoError(t, err)
+
+	_, err = service.Refresh(ctx, first.RefreshToken, models.SessionMetadata{})
+	assert.ErrorIs(t, err, utils.ErrRefreshTokenReused)
+
+	_, err = service.Refresh(ctx, second.RefreshToken, models.SessionMetadata{})
+	assert.ErrorIs(t, err, utils.ErrInvalidRefreshToken, "the session is revoked")
+}
+
+func TestRefreshRefusesForgedTokensWithoutRevoking(t *testing.T) {
+	service, user := newSQLiteTokenService(t)
+	ctx := context.Background()
+
+	pair, err := service.IssueTokens(ctx, user, models.SessionMetadata{})
+	require.NoError(t, err)
+
+	// The session ID is the sid claim and listed by GET /auth/sessions
+	sessionID, _, _ := strings.Cut(pair.RefreshToken, ".")
+	for _, forged := range []string{sessionID + ".garbage", sessionID + "." + strings.Repeat("A", 43)} {
+		_, err = service.Refresh(ctx, forged, models.SessionMetadata{})
+		assert.ErrorIs(t, err, utils.ErrInvalidRefreshToken)
+	}
+
+	// Also after a rotation, when the session has a previous token
+	pair, err = service.Refresh(ctx, pair.RefreshToken, models.SessionMetadata{})
+	require.NoError(t, err)
+	_, err = service.Refresh(ctx, sessionID+".garbage", models.SessionMetadata{})
+	assert.ErrorIs(t, err, utils.ErrInvalidRefreshToken)
+
+	_, err = service.Refresh(ctx, pair.RefreshToken, models.SessionMetadata{})
+	assert.NoError(t, err, "the session survives forged tokens")
+}
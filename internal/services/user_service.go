@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"time"
@@ -13,20 +14,22 @@ import (
 )
 
 type UserService struct {
-	userRepo interfaces.UserRepository
+	userRepo    interfaces.UserRepository
+	revocations *RevocationStore
 }
 
-func NewUserService(userRepo interfaces.UserRepository) *UserService {
+func NewUserService(userRepo interfaces.UserRepository, revocations *RevocationStore) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		revocations: revocations,
 	}
 }
 
-func (s *UserService) GetUserByID(userID uuid.UUID) (*models.User, error) {
-	return s.userRepo.GetByID(userID)
+func (s *UserService) GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	return s.userRepo.GetByID(ctx, userID)
 }
 
-func (s *UserService) GetUsers(page, limit int, search string) (*models.UsersListResponse, error) {
+func (s *UserService) GetUsers(ctx context.Context, page, limit int, search string) (*models.UsersListResponse, error) {
 	if validationErrors := utils.ValidatePaginationParams(page, limit); validationErrors.HasErrors() {
 		return nil, fmt.Errorf("validation failed: %s", validationErrors.Error())
 	}
@@ -35,7 +38,7 @@ func (s *UserService) GetUsers(page, limit int, search string) (*models.UsersLis
 		return nil, fmt.Errorf("invalid search query")
 	}
 
-	users, total, err := s.userRepo.GetUsers(page, limit, search)
+	users, total, err := s.userRepo.GetUsers(ctx, page, limit, search)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +48,7 @@ func (s *UserService) GetUsers(page, limit int, search string) (*models.UsersLis
 		userResponses[i] = models.UserResponse{
 			ID:          user.ID,
 			PhoneNumber: user.PhoneNumber,
+			Role:        user.Role,
 			CreatedAt:   user.CreatedAt.Format(time.RFC3339),
 		}
 	}
@@ -60,8 +64,8 @@ func (s *UserService) GetUsers(page, limit int, search string) (*models.UsersLis
 	}, nil
 }
 
-func (s *UserService) GetUserStats() (map[string]interface{}, error) {
-	users, _, err := s.userRepo.GetUsers(1, 1000000, "")
+func (s *UserService) GetUserStats(ctx context.Context) (map[string]interface{}, error) {
+	users, _, err := s.userRepo.GetUsers(ctx, 1, 1000000, "")
 	if err != nil {
 		return nil, err
 	}
@@ -92,3 +96,29 @@ func (s *UserService) GetUserStats() (map[string]interface{}, error) {
 		"timestamp":        now.Format(time.RFC3339),
 	}, nil
 }
+
+// UpdateRole changes a user's role. Access tokens carry the role, so the
+// user's existing tokens are revoked and pick up the new role on refresh.
+func (s *UserService) UpdateRole(ctx context.Context, actorID, userID uuid.UUID, role models.Role) (*models.User, error) {
+	if !role.IsValid() {
+		return nil, utils.ErrInvalidRole
+	}
+
+	// Keeps the last admin from locking everyone out by accident
+	if actorID == userID {
+		return nil, utils.ErrForbidden.WithDetails("cannot change your own role")
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+
+	if err := s.revocations.RevokeUserTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	utils.LogSecurityEvent(ctx, "role_changed", userID.String(), "",
+		"role set to "+string(role)+" by "+actorID.String())
+
+	return s.userRepo.GetByID(ctx, userID)
+}
@@ -0,0 +1,22 @@
+package sms
+
+import (
+	"context"
+
+	"go-auth/internal/interfaces"
+	"go-auth/pkg/utils"
+)
+
+// consoleSender logs codes instead of sending them. It goes through the
+// logger, so the phone number is masked and the code only shows with
+// LOG_REVEAL_OTP_CODES; development only.
+type consoleSender struct{}
+
+func NewConsoleSender() interfaces.OTPSender {
+	return consoleSender{}
+}
+
+func (consoleSender) Send(ctx context.Context, phoneNumber, code string) error {
+	utils.LogConsoleSMS(ctx, phoneNumber, code)
+	return nil
+}
@@ -0,0 +1,43 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go-auth/internal/interfaces"
+)
+
+// fileSender appends outgoing messages, codes included, to a local file. It
+// is meant for development only: nothing leaves the machine.
+type fileSender struct {
+	mu       sync.Mutex
+	out      io.Writer
+	template string
+}
+
+// NewFileSender creates a sink that appends messages to path
+func NewFileSender(path, template string) (interfaces.OTPSender, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SMS sink file: %w", err)
+	}
+
+	return &fileSender{out: file, template: template}, nil
+}
+
+func (s *fileSender) Send(ctx context.Context, phoneNumber, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := fmt.Fprintf(s.out, "%s\tto=%s\t%s\n",
+		time.Now().Format(time.RFC3339), phoneNumber, formatMessage(s.template, code))
+	if err != nil {
+		return fmt.Errorf("failed to write SMS to sink: %w", err)
+	}
+
+	return nil
+}
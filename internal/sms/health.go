@@ -0,0 +1,42 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// CheckHealth reports whether the gateway accepts TCP connections. Nothing is
+// sent, so probing does not cost anything with the provider.
+func (s *httpSender) CheckHealth(ctx context.Context) error {
+	gateway, err := url.Parse(s.cfg.URL)
+	if err != nil {
+		return fmt.Errorf("invalid SMS gateway URL: %w", err)
+	}
+
+	port := gateway.Port()
+	if port == "" {
+		port = "80"
+		if gateway.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return dialCheck(ctx, net.JoinHostPort(gateway.Hostname(), port))
+}
+
+// CheckHealth reports whether the SMSC accepts TCP connections. It does not
+// bind, so it cannot tell whether the credentials are still valid.
+func (s *smppSender) CheckHealth(ctx context.Context) error {
+	return dialCheck(ctx, s.cfg.Address)
+}
+
+func dialCheck(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", address, err)
+	}
+	return conn.Close()
+}
@@ -0,0 +1,58 @@
+package sms
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unusedAddress returns a local address nothing listens on
+func unusedAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return address
+}
+
+func TestHTTPSenderHealth(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("health checks must not send requests to the gateway")
+	}))
+	defer gateway.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	up := NewHTTPSender(config.HTTPSMSConfig{URL: gateway.URL}, defaultMessageTemplate)
+	assert.NoError(t, up.(interfaces.HealthChecker).CheckHealth(ctx))
+
+	down := NewHTTPSender(config.HTTPSMSConfig{URL: "http://" + unusedAddress(t)}, defaultMessageTemplate)
+	assert.Error(t, down.(interfaces.HealthChecker).CheckHealth(ctx))
+}
+
+func TestSMPPSenderHealth(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	up := NewSMPPSender(config.SMPPConfig{Address: listener.Addr().String()}, defaultMessageTemplate)
+	assert.NoError(t, up.(interfaces.HealthChecker).CheckHealth(ctx))
+
+	down := NewSMPPSender(config.SMPPConfig{Address: unusedAddress(t)}, defaultMessageTemplate)
+	assert.Error(t, down.(interfaces.HealthChecker).CheckHealth(ctx))
+}
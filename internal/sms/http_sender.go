@@ -0,0 +1,73 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// httpMessage is the JSON body posted to the SMS gateway
+type httpMessage struct {
+	To      string `json:"to"`
+	From    string `json:"from,omitempty"`
+	Message string `json:"message"`
+}
+
+// httpSender delivers messages through a generic JSON-over-HTTP SMS gateway
+type httpSender struct {
+	client   *http.Client
+	cfg      config.HTTPSMSConfig
+	template string
+}
+
+func NewHTTPSender(cfg config.HTTPSMSConfig, template string) interfaces.OTPSender {
+	return &httpSender{
+		client:   &http.Client{Timeout: cfg.Timeout},
+		cfg:      cfg,
+		template: template,
+	}
+}
+
+func (s *httpSender) Send(ctx context.Context, phoneNumber, code string) error {
+	body, err := json.Marshal(httpMessage{
+		To:      phoneNumber,
+		From:    s.cfg.From,
+		Message: formatMessage(s.template, code),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode SMS request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build SMS request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if s.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.APIKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach SMS gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS gateway returned status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	return nil
+}
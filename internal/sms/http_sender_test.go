@@ -0,0 +1,82 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPSenderDeliversMessage(t *testing.T) {
+	var received httpMessage
+	var authHeader string
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer gateway.Close()
+
+	sender := NewHTTPSender(config.HTTPSMSConfig{
+		URL:     gateway.URL,
+		APIKey:  "test-key",
+		From:    "GoAuth",
+		Timeout: time.Second,
+	}, "code: {code}")
+
+	err := sender.Send(context.Background(), "+989123456789", "123456")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer test-key", authHeader)
+	assert.Equal(t, "+989123456789", received.To)
+	assert.Equal(t, "GoAuth", received.From)
+	assert.Equal(t, "code: 123456", received.Message)
+}
+
+func TestHTTPSenderReportsGatewayErrors(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "insufficient balance", http.StatusPaymentRequired)
+	}))
+	defer gateway.Close()
+
+	sender := NewHTTPSender(config.HTTPSMSConfig{URL: gateway.URL, Timeout: time.Second}, defaultMessageTemplate)
+
+	err := sender.Send(context.Background(), "+989123456789", "123456")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "402")
+	assert.Contains(t, err.Error(), "insufficient balance")
+}
+
+func TestNewSenderRequiresProviderSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.SMSConfig
+		expectError bool
+	}{
+		{"Console default", config.SMSConfig{}, false},
+		{"HTTP without URL", config.SMSConfig{Provider: ProviderHTTP}, true},
+		{"SMPP without address", config.SMSConfig{Provider: ProviderSMPP}, true},
+		{"File without path", config.SMSConfig{Provider: ProviderFile}, true},
+		{"Unknown provider", config.SMSConfig{Provider: "pigeon"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSender(&tt.cfg)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package sms
+
+import (
+	"fmt"
+	"strings"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+)
+
+const (
+	ProviderConsole = "console"
+	ProviderFile    = "file"
+	ProviderHTTP    = "http"
+	ProviderSMPP    = "smpp"
+)
+
+const defaultMessageTemplate = "Your verification code is {code}"
+
+// NewSender builds the OTP sender selected by cfg.Provider
+func NewSender(cfg *config.SMSConfig) (interfaces.OTPSender, error) {
+	template := cfg.MessageTemplate
+	if template == "" {
+		template = defaultMessageTemplate
+	}
+
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderConsole, "":
+		return NewConsoleSender(), nil
+	case ProviderFile:
+		if cfg.FilePath == "" {
+			return nil, fmt.Errorf("SMS_FILE_PATH is required for the file provider")
+		}
+		return NewFileSender(cfg.FilePath, template)
+	case ProviderHTTP:
+		if cfg.HTTP.URL == "" {
+			return nil, fmt.Errorf("SMS_HTTP_URL is required for the http provider")
+		}
+		return NewHTTPSender(cfg.HTTP, template), nil
+	case ProviderSMPP:
+		if cfg.SMPP.Address == "" {
+			return nil, fmt.Errorf("SMPP_ADDRESS is required for the smpp provider")
+		}
+		return NewSMPPSender(cfg.SMPP, template), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider: %s", cfg.Provider)
+	}
+}
+
+// formatMessage renders the OTP code into the configured message template
+func formatMessage(template, code string) string {
+	return strings.ReplaceAll(template, "{code}", code)
+}
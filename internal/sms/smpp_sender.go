@@ -0,0 +1,233 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"go-auth/internal/config"
+	"go-auth/internal/interfaces"
+)
+
+// SMPP 3.4 command IDs used by the transmitter
+const (
+	smppBindTransmitter uint32 = 0x00000002
+	smppSubmitSM        uint32 = 0x00000004
+	smppUnbind          uint32 = 0x00000006
+	smppEnquireLink     uint32 = 0x00000015
+	smppEnquireLinkResp uint32 = 0x80000015
+	smppGenericNack     uint32 = 0x80000000
+)
+
+const (
+	smppHeaderLength     = 16
+	smppMaxPDULength     = 64 * 1024
+	smppInterfaceV34     = 0x34
+	smppTONInternational = 0x01
+	smppTONAlphanumeric  = 0x05
+	smppNPIISDN          = 0x01
+)
+
+// smppSender delivers messages to an SMSC over SMPP 3.4. Each message opens a
+// short-lived transmitter session: bind, submit_sm, unbind.
+type smppSender struct {
+	cfg      config.SMPPConfig
+	template string
+
+	mu       sync.Mutex
+	sequence uint32
+}
+
+func NewSMPPSender(cfg config.SMPPConfig, template string) interfaces.OTPSender {
+	return &smppSender{cfg: cfg, template: template}
+}
+
+func (s *smppSender) Send(ctx context.Context, phoneNumber, code string) error {
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", s.cfg.Address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMSC: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return fmt.Errorf("failed to set SMSC deadline: %w", err)
+		}
+	}
+
+	if err := s.call(conn, smppBindTransmitter, s.bindBody()); err != nil {
+		return fmt.Errorf("SMPP bind failed: %w", err)
+	}
+
+	if err := s.call(conn, smppSubmitSM, s.submitBody(phoneNumber, formatMessage(s.template, code))); err != nil {
+		return fmt.Errorf("SMPP submit_sm failed: %w", err)
+	}
+
+	// The message has been accepted at this point, so a failed unbind is not
+	// reported as a delivery failure.
+	_ = s.call(conn, smppUnbind, nil)
+
+	return nil
+}
+
+// call writes a request PDU and waits for its matching response
+func (s *smppSender) call(conn net.Conn, commandID uint32, body []byte) error {
+	sequence := s.nextSequence()
+	if err := writePDU(conn, commandID, 0, sequence, body); err != nil {
+		return err
+	}
+
+	for {
+		respID, status, respSeq, _, err := readPDU(conn)
+		if err != nil {
+			return err
+		}
+
+		// The SMSC may probe the session while we wait for our response
+		if respID == smppEnquireLink {
+			if err := writePDU(conn, smppEnquireLinkResp, 0, respSeq, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if respID == smppGenericNack {
+			return fmt.Errorf("generic_nack with status 0x%08x", status)
+		}
+
+		if respID != commandID|smppGenericNack || respSeq != sequence {
+			return fmt.Errorf("unexpected PDU 0x%08x (sequence %d)", respID, respSeq)
+		}
+
+		if status != 0 {
+			return fmt.Errorf("command status 0x%08x", status)
+		}
+
+		return nil
+	}
+}
+
+func (s *smppSender) nextSequence() uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sequence++
+	if s.sequence > 0x7FFFFFFF {
+		s.sequence = 1
+	}
+	return s.sequence
+}
+
+func (s *smppSender) bindBody() []byte {
+	var buf bytes.Buffer
+	writeCString(&buf, s.cfg.SystemID)
+	writeCString(&buf, s.cfg.Password)
+	writeCString(&buf, s.cfg.SystemType)
+	buf.WriteByte(smppInterfaceV34)
+	buf.WriteByte(0) // addr_ton
+	buf.WriteByte(0) // addr_npi
+	writeCString(&buf, "")
+	return buf.Bytes()
+}
+
+func (s *smppSender) submitBody(phoneNumber, message string) []byte {
+	sourceTON := byte(smppTONAlphanumeric)
+	sourceNPI := byte(0)
+	if isNumericAddress(s.cfg.SourceAddr) {
+		sourceTON = smppTONInternational
+		sourceNPI = smppNPIISDN
+	}
+
+	// short_message is limited to 254 octets; OTP messages are far shorter
+	if len(message) > 254 {
+		message = message[:254]
+	}
+
+	var buf bytes.Buffer
+	writeCString(&buf, "") // service_type
+	buf.WriteByte(sourceTON)
+	buf.WriteByte(sourceNPI)
+	writeCString(&buf, strings.TrimPrefix(s.cfg.SourceAddr, "+"))
+	buf.WriteByte(smppTONInternational)
+	buf.WriteByte(smppNPIISDN)
+	writeCString(&buf, strings.TrimPrefix(phoneNumber, "+"))
+	buf.WriteByte(0)       // esm_class
+	buf.WriteByte(0)       // protocol_id
+	buf.WriteByte(0)       // priority_flag
+	writeCString(&buf, "") // schedule_delivery_time
+	writeCString(&buf, "") // validity_period
+	buf.WriteByte(0)       // registered_delivery
+	buf.WriteByte(0)       // replace_if_present_flag
+	buf.WriteByte(0)       // data_coding: SMSC default alphabet
+	buf.WriteByte(0)       // sm_default_msg_id
+	buf.WriteByte(byte(len(message)))
+	buf.WriteString(message)
+	return buf.Bytes()
+}
+
+func writePDU(w io.Writer, commandID, status, sequence uint32, body []byte) error {
+	pdu := make([]byte, smppHeaderLength+len(body))
+	binary.BigEndian.PutUint32(pdu[0:4], uint32(len(pdu)))
+	binary.BigEndian.PutUint32(pdu[4:8], commandID)
+	binary.BigEndian.PutUint32(pdu[8:12], status)
+	binary.BigEndian.PutUint32(pdu[12:16], sequence)
+	copy(pdu[smppHeaderLength:], body)
+
+	if _, err := w.Write(pdu); err != nil {
+		return fmt.Errorf("failed to write PDU: %w", err)
+	}
+	return nil
+}
+
+func readPDU(r io.Reader) (commandID, status, sequence uint32, body []byte, err error) {
+	header := make([]byte, smppHeaderLength)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("failed to read PDU header: %w", err)
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length < smppHeaderLength || length > smppMaxPDULength {
+		return 0, 0, 0, nil, fmt.Errorf("invalid PDU length %d", length)
+	}
+
+	commandID = binary.BigEndian.Uint32(header[4:8])
+	status = binary.BigEndian.Uint32(header[8:12])
+	sequence = binary.BigEndian.Uint32(header[12:16])
+
+	body = make([]byte, length-smppHeaderLength)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("failed to read PDU body: %w", err)
+	}
+
+	return commandID, status, sequence, body, nil
+}
+
+func writeCString(buf *bytes.Buffer, value string) {
+	buf.WriteString(value)
+	buf.WriteByte(0)
+}
+
+func isNumericAddress(addr string) bool {
+	addr = strings.TrimPrefix(addr, "+")
+	if addr == "" {
+		return false
+	}
+	for _, char := range addr {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
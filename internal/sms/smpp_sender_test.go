@@ -0,0 +1,90 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMSC accepts a single transmitter session and records the commands it sees
+func fakeSMSC(t *testing.T, submitStatus uint32) (string, <-chan []uint32, <-chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	commands := make(chan []uint32, 1)
+	submitted := make(chan []byte, 1)
+
+	go func() {
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var seen []uint32
+		defer func() { commands <- seen }()
+
+		for {
+			commandID, _, sequence, body, err := readPDU(conn)
+			if err != nil {
+				return
+			}
+			seen = append(seen, commandID)
+
+			status := uint32(0)
+			if commandID == smppSubmitSM {
+				submitted <- body
+				status = submitStatus
+			}
+
+			if err := writePDU(conn, commandID|smppGenericNack, status, sequence, nil); err != nil {
+				return
+			}
+			if commandID == smppUnbind || status != 0 {
+				return
+			}
+		}
+	}()
+
+	return listener.Addr().String(), commands, submitted
+}
+
+func TestSMPPSenderSubmitsMessage(t *testing.T) {
+	addr, commands, submitted := fakeSMSC(t, 0)
+
+	sender := NewSMPPSender(config.SMPPConfig{
+		Address:    addr,
+		SystemID:   "goauth",
+		Password:   "secret",
+		SourceAddr: "GoAuth",
+		Timeout:    time.Second,
+	}, "code: {code}")
+
+	err := sender.Send(context.Background(), "+989123456789", "123456")
+	require.NoError(t, err)
+
+	body := <-submitted
+	assert.True(t, bytes.Contains(body, []byte("989123456789\x00")))
+	assert.True(t, bytes.HasSuffix(body, []byte("code: 123456")))
+	assert.Equal(t, []uint32{smppBindTransmitter, smppSubmitSM, smppUnbind}, <-commands)
+}
+
+func TestSMPPSenderReportsRejectedSubmit(t *testing.T) {
+	addr, _, _ := fakeSMSC(t, 0x00000045)
+
+	sender := NewSMPPSender(config.SMPPConfig{Address: addr, Timeout: time.Second}, defaultMessageTemplate)
+
+	err := sender.Send(context.Background(), "+989123456789", "123456")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "0x00000045")
+}
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// InstrumentGORM wraps every query run through db in a client span, parented
+// by the span in the statement's context (see gorm.DB.WithContext). Only the
+// SQL text with placeholders is recorded, never the bound values.
+func InstrumentGORM(db *gorm.DB) error {
+	callback := db.Callback()
+
+	register := []struct {
+		operation string
+		before    error
+		after     error
+	}{
+		{"create", callback.Create().Before("gorm:create").Register("tracing:before_create", before("create")),
+			callback.Create().After("gorm:create").Register("tracing:after_create", after)},
+		{"query", callback.Query().Before("gorm:query").Register("tracing:before_query", before("query")),
+			callback.Query().After("gorm:query").Register("tracing:after_query", after)},
+		{"update", callback.Update().Before("gorm:update").Register("tracing:before_update", before("update")),
+			callback.Update().After("gorm:update").Register("tracing:after_update", after)},
+		{"delete", callback.Delete().Before("gorm:delete").Register("tracing:before_delete", before("delete")),
+			callback.Delete().After("gorm:delete").Register("tracing:after_delete", after)},
+		{"row", callback.Row().Before("gorm:row").Register("tracing:before_row", before("row")),
+			callback.Row().After("gorm:row").Register("tracing:after_row", after)},
+		{"raw", callback.Raw().Before("gorm:raw").Register("tracing:before_raw", before("raw")),
+			callback.Raw().After("gorm:raw").Register("tracing:after_raw", after)},
+	}
+
+	for _, r := range register {
+		if err := errors.Join(r.before, r.after); err != nil {
+			return fmt.Errorf("failed to register %s tracing callbacks: %w", r.operation, err)
+		}
+	}
+
+	return nil
+}
+
+func before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			return
+		}
+
+		system := semconv.DBSystemPostgreSQL
+		if db.Dialector.Name() == "sqlite" {
+			system = semconv.DBSystemSqlite
+		}
+
+		_, span := Tracer().Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				system,
+				semconv.DBOperationName(operation),
+			),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	if table := db.Statement.Table; table != "" {
+		span.SetAttributes(semconv.DBCollectionName(table))
+	}
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
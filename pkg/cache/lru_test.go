@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, _ = c.Get("a")
+	c.Set("c", 3)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok, "b should have been evicted")
+
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	value, ok = c.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	c := NewLRU[string, bool](10, 10*time.Millisecond)
+
+	c.Set("token", true)
+	_, ok := c.Get("token")
+	assert.True(t, ok)
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, ok = c.Get("token")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRUDelete(t *testing.T) {
+	c := NewLRU[int, string](10, time.Minute)
+
+	c.Set(1, "one")
+	c.Delete(1)
+
+	_, ok := c.Get(1)
+	assert.False(t, ok)
+}
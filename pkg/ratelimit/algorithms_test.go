@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a settable time source for limiters
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func allow(t *testing.T, limiter Limiter) Result {
+	t.Helper()
+
+	result, err := limiter.Allow(context.Background(), "key")
+	require.NoError(t, err)
+	return result
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clk := &clock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewSlidingWindow(NewMemoryStore(), "test", 4, time.Minute)
+	limiter.now = clk.Now
+
+	for i := 3; i >= 0; i-- {
+		result := allow(t, limiter)
+		require.True(t, result.Allowed)
+		assert.Equal(t, 4, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	denied := allow(t, limiter)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, 0, denied.Remaining)
+	// All four requests are in this window; room for one more once three
+	// quarters of them have faded out of the next one
+	assert.Equal(t, time.Minute+15*time.Second, denied.RetryAfter)
+	assert.Equal(t, 2*time.Minute, denied.ResetAfter)
+
+	// Half way through the next window two of the four still count
+	clk.Advance(90 * time.Second)
+	result := allow(t, limiter)
+	require.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+	assert.True(t, allow(t, limiter).Allowed)
+	assert.False(t, allow(t, limiter).Allowed)
+
+	// Two windows later everything has faded
+	clk.Advance(2 * time.Minute)
+	assert.Equal(t, 3, allow(t, limiter).Remaining)
+}
+
+func TestSlidingWindowRetryAfterIsAccurate(t *testing.T) {
+	clk := &clock{now: time.Date(2025, 1, 1, 12, 0, 50, 0, time.UTC)}
+	limiter := NewSlidingWindow(NewMemoryStore(), "test", 3, time.Minute)
+	limiter.now = clk.Now
+
+	for i := 0; i < 3; i++ {
+		require.True(t, allow(t, limiter).Allowed)
+	}
+
+	clk.Advance(20 * time.Second)
+	denied := allow(t, limiter)
+	require.False(t, denied.Allowed)
+
+	clk.Advance(denied.RetryAfter - time.Second)
+	assert.False(t, allow(t, limiter).Allowed, "denied just before RetryAfter")
+
+	clk.Advance(time.Second)
+	assert.True(t, allow(t, limiter).Allowed, "allowed at RetryAfter")
+}
+
+func TestSlidingWindowRefund(t *testing.T) {
+	clk := &clock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewSlidingWindow(NewMemoryStore(), "test", 2, time.Minute)
+	limiter.now = clk.Now
+	ctx := context.Background()
+
+	require.True(t, allow(t, limiter).Allowed)
+	require.True(t, allow(t, limiter).Allowed)
+	require.NoError(t, limiter.Refund(ctx, "key"))
+	assert.True(t, allow(t, limiter).Allowed, "a refunded request frees its room")
+	assert.False(t, allow(t, limiter).Allowed)
+
+	// A request counted just before the window rolled over is refunded from
+	// the previous window
+	clk.Advance(time.Minute)
+	require.False(t, allow(t, limiter).Allowed)
+	require.NoError(t, limiter.Refund(ctx, "key"))
+	assert.True(t, allow(t, limiter).Allowed)
+
+	// Refunding an unknown key does nothing
+	require.NoError(t, limiter.Refund(ctx, "other"))
+	result, err := limiter.Allow(ctx, "other")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Remaining)
+}
+
+func TestTokenBucket(t *testing.T) {
+	clk := &clock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewTokenBucket(NewMemoryStore(), "test", 3, 3*time.Second)
+	limiter.now = clk.Now
+
+	// A full bucket allows a burst
+	for i := 2; i >= 0; i-- {
+		result := allow(t, limiter)
+		require.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	denied := allow(t, limiter)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, time.Second, denied.RetryAfter)
+	assert.Equal(t, 3*time.Second, denied.ResetAfter)
+
+	clk.Advance(time.Second)
+	assert.True(t, allow(t, limiter).Allowed)
+	assert.False(t, allow(t, limiter).Allowed)
+
+	// Refills stop at capacity
+	clk.Advance(time.Hour)
+	assert.Equal(t, 2, allow(t, limiter).Remaining)
+}
+
+func TestLimitersUseSeparateKeys(t *testing.T) {
+	store := NewMemoryStore()
+	first := NewTokenBucket(store, "first", 1, time.Minute)
+	second := NewTokenBucket(store, "second", 1, time.Minute)
+
+	assert.True(t, allow(t, first).Allowed)
+	assert.True(t, allow(t, second).Allowed, "limiters sharing a store do not share keys")
+
+	result, err := first.Allow(context.Background(), "other")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
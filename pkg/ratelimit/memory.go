@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many updates the memory store makes between removing
+// expired keys
+const sweepEvery = 1024
+
+type memoryEntry struct {
+	state     State
+	expiresAt time.Time
+}
+
+// MemoryStore keeps state in process. Limits are per replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	updates int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(State) State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	s.updates++
+	if s.updates%sweepEvery == 0 {
+		for k, entry := range s.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	var state State
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expiresAt) {
+		state = entry.state
+	}
+
+	s.entries[key] = memoryEntry{state: fn(state), expiresAt: now.Add(ttl)}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package ratelimit limits how often a key, e.g. a client IP or a phone
+// number, may do something.
+//
+// A Limiter implements the algorithm and keeps its per-key State in a Store.
+// Stores only need to update one key atomically, so every algorithm works on
+// every store: in memory for a single replica, or shared through the
+// database or Redis.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Result is the outcome of one Allow call
+type Result struct {
+	Allowed bool
+	// Limit is the number of requests allowed per period
+	Limit int
+	// Remaining is the number of requests left, after this one
+	Remaining int
+	// RetryAfter is when a denied request may be retried
+	RetryAfter time.Duration
+	// ResetAfter is when the full limit is available again
+	ResetAfter time.Duration
+}
+
+// Limiter decides whether key may make another request, and counts it if so
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// State is what a limiter keeps per key. Its meaning depends on the
+// algorithm; the zero State is a key that has not been seen.
+type State struct {
+	// Count is the number of requests in the current window, or the tokens
+	// left in a bucket
+	Count float64
+	// Previous is the number of requests in the previous window
+	Previous float64
+	// Time is the start of the current window, or the last refill
+	Time time.Time
+}
+
+// Store keeps limiter state
+type Store interface {
+	// Update replaces the state of key with the result of fn, atomically
+	// with respect to other updates of the same key, and keeps it for ttl.
+	// fn gets the zero State for an unknown or expired key. It may be called
+	// more than once, so it must not have side effects.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(State) State) error
+}
+
+// SlidingWindow allows limit requests in any window of the given length.
+// It counts requests in fixed windows and weighs the previous window by how
+// much of it still overlaps the sliding one, so each key needs two counters
+// rather than a log of its requests.
+type SlidingWindow struct {
+	store  Store
+	name   string
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+// NewSlidingWindow stores its state under keys starting with name, which
+// must be unique per limiter sharing a store
+func NewSlidingWindow(store Store, name string, limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{store: store, name: name, limit: limit, window: window, now: time.Now}
+}
+
+func (l *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	var result Result
+	err := l.store.Update(ctx, l.name+":"+key, 2*l.window, func(state State) State {
+		state, result = l.take(state, l.now())
+		return state
+	})
+	return result, err
+}
+
+func (l *SlidingWindow) take(state State, now time.Time) (State, Result) {
+	start := now.Truncate(l.window)
+	switch {
+	case state.Time.Equal(start):
+	case state.Time.Equal(start.Add(-l.window)):
+		state = State{Previous: state.Count, Time: start}
+	default:
+		state = State{Time: start}
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(l.window)
+	used := state.Previous*weight + state.Count
+	limit := float64(l.limit)
+
+	result := Result{Limit: l.limit}
+	if used+1 > limit {
+		result.RetryAfter = l.retryAfter(state, elapsed)
+		result.ResetAfter = l.resetAfter(state, elapsed)
+		return state, result
+	}
+
+	state.Count++
+	result.Allowed = true
+	result.Remaining = int(math.Floor(limit - used - 1))
+	result.ResetAfter = l.resetAfter(state, elapsed)
+	return state, result
+}
+
+// Refund gives back a request Allow counted for key, e.g. because a later
+// check refused what it was counted for. Requests that have since left the
+// window are not given back.
+func (l *SlidingWindow) Refund(ctx context.Context, key string) error {
+	return l.store.Update(ctx, l.name+":"+key, 2*l.window, func(state State) State {
+		return l.refund(state, l.now())
+	})
+}
+
+func (l *SlidingWindow) refund(state State, now time.Time) State {
+	start := now.Truncate(l.window)
+	switch {
+	// Counted in this window, or in the last one if no request has rolled
+	// the state over since
+	case state.Count > 0 && (state.Time.Equal(start) || state.Time.Equal(start.Add(-l.window))):
+		state.Count--
+	// Counted just before the window rolled over
+	case state.Time.Equal(start) && state.Previous > 0:
+		state.Previous--
+	}
+	return state
+}
+
+// retryAfter is how long until the weighted count leaves room for one more
+// request
+func (l *SlidingWindow) retryAfter(state State, elapsed time.Duration) time.Duration {
+	room := float64(l.limit) - 1
+	window := float64(l.window)
+
+	// The previous window fading out is enough
+	if state.Count <= room && state.Previous > 0 {
+		at := window * (1 - (room-state.Count)/state.Previous)
+		return time.Duration(at) - elapsed
+	}
+
+	// Otherwise wait for this window to become the previous one and fade
+	if room <= 0 {
+		return 2*l.window - elapsed
+	}
+	at := window * (1 - room/state.Count)
+	return l.window + time.Duration(math.Max(at, 0)) - elapsed
+}
+
+// resetAfter is how long until every counted request has left the window
+func (l *SlidingWindow) resetAfter(state State, elapsed time.Duration) time.Duration {
+	switch {
+	case state.Count > 0:
+		return 2*l.window - elapsed
+	case state.Previous > 0:
+		return l.window - elapsed
+	default:
+		return 0
+	}
+}
+
+// TokenBucket allows bursts of up to capacity requests and refills at
+// capacity tokens per period
+type TokenBucket struct {
+	store    Store
+	name     string
+	capacity int
+	period   time.Duration
+	now      func() time.Time
+}
+
+// NewTokenBucket stores its state under keys starting with name, which must
+// be unique per limiter sharing a store
+func NewTokenBucket(store Store, name string, capacity int, period time.Duration) *TokenBucket {
+	return &TokenBucket{store: store, name: name, capacity: capacity, period: period, now: time.Now}
+}
+
+func (l *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	var result Result
+	err := l.store.Update(ctx, l.name+":"+key, l.period, func(state State) State {
+		state, result = l.take(state, l.now())
+		return state
+	})
+	return result, err
+}
+
+func (l *TokenBucket) take(state State, now time.Time) (State, Result) {
+	capacity := float64(l.capacity)
+	perToken := float64(l.period) / capacity
+
+	tokens := capacity
+	if !state.Time.IsZero() {
+		refilled := float64(now.Sub(state.Time)) / perToken
+		tokens = math.Min(capacity, state.Count+math.Max(refilled, 0))
+	}
+
+	result := Result{Limit: l.capacity}
+	if tokens < 1 {
+		result.RetryAfter = time.Duration((1 - tokens) * perToken)
+	} else {
+		tokens--
+		result.Allowed = true
+		result.Remaining = int(math.Floor(tokens))
+	}
+	result.ResetAfter = time.Duration((capacity - tokens) * perToken)
+
+	return State{Count: tokens, Time: now}, result
+}
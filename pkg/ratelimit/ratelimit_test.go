@@ -0,0 +1,61 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-auth/pkg/ratelimit"
+	"go-auth/pkg/ratelimit/ratelimittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ratelimittest.Run(t, func(t *testing.T) ratelimit.Store {
+		return ratelimit.NewMemoryStore()
+	})
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	ctx := context.Background()
+
+	require.NoError(t, store.Update(ctx, "key", 10*time.Millisecond, func(ratelimit.State) ratelimit.State {
+		return ratelimit.State{Count: 1, Time: time.Now()}
+	}))
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, store.Update(ctx, "key", time.Minute, func(state ratelimit.State) ratelimit.State {
+		assert.Equal(t, ratelimit.State{}, state, "an expired key starts over")
+		return state
+	}))
+}
+
+func TestLimitersAreConcurrencySafe(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limiters := []ratelimit.Limiter{
+		ratelimit.NewSlidingWindow(store, "window", 5, time.Hour),
+		ratelimit.NewTokenBucket(store, "bucket", 5, time.Hour),
+	}
+
+	for _, limiter := range limiters {
+		allowed := make(chan bool, 20)
+		for i := 0; i < 20; i++ {
+			go func() {
+				result, err := limiter.Allow(context.Background(), "+15550000001")
+				assert.NoError(t, err)
+				allowed <- result.Allowed
+			}()
+		}
+
+		count := 0
+		for i := 0; i < 20; i++ {
+			if <-allowed {
+				count++
+			}
+		}
+		assert.Equal(t, 5, count, "%T", limiter)
+	}
+}
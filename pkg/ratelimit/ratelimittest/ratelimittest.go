@@ -0,0 +1,106 @@
+// Package ratelimittest is a conformance suite for ratelimit.Store
+// implementations
+package ratelimittest
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"go-auth/pkg/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the suite against the store returned by newStore, which is called
+// once per test. Every test uses keys of its own, so stores may be shared.
+func Run(t *testing.T, newStore func(t *testing.T) ratelimit.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store ratelimit.Store)
+	}{
+		{"UnknownKey", testUnknownKey},
+		{"RoundTrip", testRoundTrip},
+		{"KeysAreSeparate", testKeysAreSeparate},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func uniqueKey() string {
+	return fmt.Sprintf("test:%d", rand.Int64())
+}
+
+// read returns the stored state of key, leaving it unchanged
+func read(t *testing.T, store ratelimit.Store, key string) ratelimit.State {
+	t.Helper()
+
+	var state ratelimit.State
+	require.NoError(t, store.Update(context.Background(), key, time.Minute, func(current ratelimit.State) ratelimit.State {
+		state = current
+		return current
+	}))
+	return state
+}
+
+func testUnknownKey(t *testing.T, store ratelimit.Store) {
+	assert.Equal(t, ratelimit.State{}, read(t, store, uniqueKey()))
+}
+
+func testRoundTrip(t *testing.T, store ratelimit.Store) {
+	key := uniqueKey()
+	at := time.Now().Truncate(time.Second)
+	want := ratelimit.State{Count: 2.5, Previous: 7, Time: at}
+
+	require.NoError(t, store.Update(context.Background(), key, time.Minute, func(ratelimit.State) ratelimit.State {
+		return want
+	}))
+
+	got := read(t, store, key)
+	assert.Equal(t, want.Count, got.Count)
+	assert.Equal(t, want.Previous, got.Previous)
+	assert.True(t, want.Time.Equal(got.Time), "stored %s, read %s", want.Time, got.Time)
+}
+
+func testKeysAreSeparate(t *testing.T, store ratelimit.Store) {
+	key := uniqueKey()
+	require.NoError(t, store.Update(context.Background(), key, time.Minute, func(ratelimit.State) ratelimit.State {
+		return ratelimit.State{Count: 1, Time: time.Now()}
+	}))
+
+	assert.Equal(t, ratelimit.State{}, read(t, store, key+":other"))
+}
+
+func testConcurrentUpdates(t *testing.T, store ratelimit.Store) {
+	key := uniqueKey()
+	const updates = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, updates)
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- store.Update(context.Background(), key, time.Minute, func(state ratelimit.State) ratelimit.State {
+				state.Count++
+				return state
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, float64(updates), read(t, store, key).Count, "no update may be lost")
+}
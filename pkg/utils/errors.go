@@ -1,15 +1,24 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
+// StatusClientClosedRequest is the non-standard status logged when the client
+// went away before the response was ready
+const StatusClientClosedRequest = 499
+
 type AppError struct {
-	Code     string `json:"code"`
-	Message  string `json:"message"`
-	HTTPCode int    `json:"-"`
-	Details  string `json:"details,omitempty"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	HTTPCode   int    `json:"-"`
+	Details    string `json:"details,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"`
 }
 
 func (e *AppError) Error() string {
@@ -44,6 +53,42 @@ var (
 		HTTPCode: http.StatusTooManyRequests,
 	}
 
+	ErrOTPDeliveryFailed = &AppError{
+		Code:     "OTP_DELIVERY_FAILED",
+		Message:  "Failed to deliver OTP. Please try again later",
+		HTTPCode: http.StatusBadGateway,
+	}
+
+	ErrDestinationNotAllowed = &AppError{
+		Code:     "DESTINATION_NOT_ALLOWED",
+		Message:  "Phone numbers in this region are not supported",
+		HTTPCode: http.StatusForbidden,
+	}
+
+	ErrDestinationSuspended = &AppError{
+		Code:     "DESTINATION_SUSPENDED",
+		Message:  "Sending codes to this region is temporarily suspended. Please try again later",
+		HTTPCode: http.StatusServiceUnavailable,
+	}
+
+	ErrChallengeRequired = &AppError{
+		Code:     "CHALLENGE_REQUIRED",
+		Message:  "Please solve the challenge and try again",
+		HTTPCode: http.StatusPreconditionRequired,
+	}
+
+	ErrChallengeFailed = &AppError{
+		Code:     "CHALLENGE_FAILED",
+		Message:  "Challenge solution is invalid or expired. Please solve the new challenge",
+		HTTPCode: http.StatusPreconditionRequired,
+	}
+
+	ErrChallengeUnavailable = &AppError{
+		Code:     "CHALLENGE_UNAVAILABLE",
+		Message:  "The challenge could not be checked. Please try again later",
+		HTTPCode: http.StatusServiceUnavailable,
+	}
+
 	ErrUserNotFound = &AppError{
 		Code:     "USER_NOT_FOUND",
 		Message:  "User not found",
@@ -62,6 +107,42 @@ var (
 		HTTPCode: http.StatusUnauthorized,
 	}
 
+	ErrTokenRevoked = &AppError{
+		Code:     "TOKEN_REVOKED",
+		Message:  "Token has been revoked",
+		HTTPCode: http.StatusUnauthorized,
+	}
+
+	ErrSessionNotFound = &AppError{
+		Code:     "SESSION_NOT_FOUND",
+		Message:  "Session not found",
+		HTTPCode: http.StatusNotFound,
+	}
+
+	ErrInvalidRefreshToken = &AppError{
+		Code:     "INVALID_REFRESH_TOKEN",
+		Message:  "Invalid or expired refresh token",
+		HTTPCode: http.StatusUnauthorized,
+	}
+
+	ErrRefreshTokenReused = &AppError{
+		Code:     "REFRESH_TOKEN_REUSED",
+		Message:  "Refresh token has already been used. Please sign in again",
+		HTTPCode: http.StatusUnauthorized,
+	}
+
+	ErrForbidden = &AppError{
+		Code:     "FORBIDDEN",
+		Message:  "You do not have permission to perform this action",
+		HTTPCode: http.StatusForbidden,
+	}
+
+	ErrInvalidRole = &AppError{
+		Code:     "INVALID_ROLE",
+		Message:  "Unknown role",
+		HTTPCode: http.StatusBadRequest,
+	}
+
 	ErrInternalServer = &AppError{
 		Code:     "INTERNAL_SERVER_ERROR",
 		Message:  "Internal server error",
@@ -79,6 +160,18 @@ var (
 		Message:  "Validation failed",
 		HTTPCode: http.StatusBadRequest,
 	}
+
+	ErrRequestCanceled = &AppError{
+		Code:     "REQUEST_CANCELED",
+		Message:  "Request was canceled",
+		HTTPCode: StatusClientClosedRequest,
+	}
+
+	ErrRequestTimeout = &AppError{
+		Code:     "REQUEST_TIMEOUT",
+		Message:  "Request timed out. Please try again",
+		HTTPCode: http.StatusGatewayTimeout,
+	}
 )
 
 func NewAppError(code, message string, httpCode int) *AppError {
@@ -91,10 +184,26 @@ func NewAppError(code, message string, httpCode int) *AppError {
 
 func (e *AppError) WithDetails(details string) *AppError {
 	return &AppError{
-		Code:     e.Code,
-		Message:  e.Message,
-		HTTPCode: e.HTTPCode,
-		Details:  details,
+		Code:       e.Code,
+		Message:    e.Message,
+		HTTPCode:   e.HTTPCode,
+		Details:    details,
+		RetryAfter: e.RetryAfter,
+	}
+}
+
+// NewOTPLockedError tells the client verification is blocked for the given duration
+func NewOTPLockedError(wait time.Duration) *AppError {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return &AppError{
+		Code:       "OTP_VERIFICATION_LOCKED",
+		Message:    fmt.Sprintf("Too many failed attempts. Please try again in %d seconds", seconds),
+		HTTPCode:   http.StatusTooManyRequests,
+		RetryAfter: seconds,
 	}
 }
 
@@ -105,10 +214,20 @@ func IsAppError(err error) (*AppError, bool) {
 	return nil, false
 }
 
+// HandleError maps err to the response it deserves. Cancellations and
+// deadlines surface from any layer wrapped in other errors, so they are
+// matched with errors.Is rather than by type.
 func HandleError(err error) *AppError {
 	if appErr, ok := IsAppError(err); ok {
 		return appErr
 	}
 
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrRequestCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrRequestTimeout
+	}
+
 	return ErrInternalServer.WithDetails(err.Error())
 }
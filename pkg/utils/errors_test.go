@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *AppError
+	}{
+		{"app error", ErrUserNotFound, ErrUserNotFound},
+		{"canceled", fmt.Errorf("failed to get user by ID: %w", context.Canceled), ErrRequestCanceled},
+		{"deadline", fmt.Errorf("failed to count users: %w", context.DeadlineExceeded), ErrRequestTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HandleError(tt.err))
+		})
+	}
+
+	other := HandleError(errors.New("connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, other.HTTPCode)
+	assert.Equal(t, ErrInternalServer.Code, other.Code)
+}
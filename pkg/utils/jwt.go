@@ -1,7 +1,19 @@
 package utils
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,26 +21,333 @@ import (
 )
 
 type JWTClaims struct {
-	UserID      uuid.UUID `json:"user_id"`
-	PhoneNumber string    `json:"phone_number"`
+	UserID       uuid.UUID `json:"user_id"`
+	PhoneNumber  string    `json:"phone_number"`
+	Role         string    `json:"role,omitempty"`
+	Permissions  []string  `json:"permissions,omitempty"`
+	TokenVersion int       `json:"tv"`
+	SessionID    uuid.UUID `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID uuid.UUID, phoneNumber, secret string) (string, error) {
+// HasRole reports whether the token was issued for one of the given roles
+func (c *JWTClaims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if c.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the token grants the given permission
+func (c *JWTClaims) HasPermission(permission string) bool {
+	for _, granted := range c.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenSubject describes the user an access token is issued for
+type TokenSubject struct {
+	UserID       uuid.UUID
+	PhoneNumber  string
+	Role         string
+	Permissions  []string
+	TokenVersion int
+	SessionID    uuid.UUID
+}
+
+// SigningKey is a JWT key identified by its kid. HMAC keys sign and verify
+// with the same secret; asymmetric keys publish their public half as a JWK.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// JWK is the public part of a signing key in RFC 7517 form
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACSigningKey creates an HS256 key from a shared secret
+func NewHMACSigningKey(kid, secret string) *SigningKey {
+	return &SigningKey{
+		ID:        kid,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// LoadSigningKey reads a PEM encoded private key for RS256, ES256 or EdDSA.
+// When kid is empty the RFC 7638 thumbprint of the public key is used.
+func LoadSigningKey(kid, algorithm, privateKeyFile string) (*SigningKey, error) {
+	pemData, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+
+	key, err := ParseSigningKey(kid, algorithm, pemData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT private key %s: %w", privateKeyFile, err)
+	}
+
+	return key, nil
+}
+
+// ParseSigningKey builds a signing key from PEM encoded private key bytes
+func ParseSigningKey(kid, algorithm string, pemData []byte) (*SigningKey, error) {
+	key := &SigningKey{ID: kid}
+
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, privateKey, &privateKey.PublicKey
+	case jwt.SigningMethodES256.Alg():
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		if privateKey.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 key")
+		}
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodES256, privateKey, &privateKey.PublicKey
+	case jwt.SigningMethodEdDSA.Alg():
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := privateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("EdDSA requires an Ed25519 key")
+		}
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodEdDSA, edKey, edKey.Public()
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", algorithm)
+	}
+
+	if key.ID == "" {
+		thumbprint, err := key.thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		key.ID = thumbprint
+	}
+
+	return key, nil
+}
+
+// PublicJWK returns the key's public half. HMAC keys have none.
+func (k *SigningKey) PublicJWK() (JWK, bool) {
+	jwk := JWK{Use: "sig", Kid: k.ID, Alg: k.Method.Alg()}
+
+	switch publicKey := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		byteLen := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, byteLen)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, byteLen)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint used as the default kid
+func (k *SigningKey) thumbprint() (string, error) {
+	jwk, ok := k.PublicJWK()
+	if !ok {
+		return "", errors.New("thumbprint requires an asymmetric key")
+	}
+
+	// Required members only, in lexicographic order
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	encoded, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// Keyring holds the key new tokens are signed with plus verification-only
+// keys, looked up by kid. Verification keys may carry a retirement time after
+// which tokens signed with them are no longer accepted or published.
+type Keyring struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]keyringEntry
+}
+
+type keyringEntry struct {
+	key      *SigningKey
+	retireAt time.Time
+}
+
+func NewKeyring(active *SigningKey) *Keyring {
+	return &Keyring{
+		active: active,
+		keys:   map[string]keyringEntry{active.ID: {key: active}},
+	}
+}
+
+// AddVerificationKey accepts tokens signed with key until retireAt. A zero
+// retireAt keeps the key until it is removed from the keyring.
+func (k *Keyring) AddVerificationKey(key *SigningKey, retireAt time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key.ID == k.active.ID {
+		return
+	}
+	k.keys[key.ID] = keyringEntry{key: key, retireAt: retireAt}
+}
+
+// Active returns the key new tokens are signed with
+func (k *Keyring) Active() *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// Lookup returns the verification key for kid unless it has been retired
+func (k *Keyring) Lookup(kid string) (*SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	entry, ok := k.keys[kid]
+	if !ok || entry.retired(time.Now()) {
+		return nil, false
+	}
+	return entry.key, true
+}
+
+// PublicJWKs returns the public halves of every key still accepted, active first
+func (k *Keyring) PublicJWKs() []JWK {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	jwks := []JWK{}
+	if jwk, ok := k.active.PublicJWK(); ok {
+		jwks = append(jwks, jwk)
+	}
+
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	for _, kid := range kids {
+		entry := k.keys[kid]
+		if kid == k.active.ID || entry.retired(now) {
+			continue
+		}
+		if jwk, ok := entry.key.PublicJWK(); ok {
+			jwks = append(jwks, jwk)
+		}
+	}
+
+	return jwks
+}
+
+// Replace swaps in the contents of another keyring, e.g. after a reload
+func (k *Keyring) Replace(other *Keyring) {
+	other.mu.RLock()
+	active, keys := other.active, other.keys
+	other.mu.RUnlock()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.active = active
+	k.keys = keys
+}
+
+func (e keyringEntry) retired(now time.Time) bool {
+	return !e.retireAt.IsZero() && now.After(e.retireAt)
+}
+
+// GenerateJWT signs an access token. The iss claim is only set when issuer is
+// not empty.
+func GenerateJWT(subject TokenSubject, key *SigningKey, issuer string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := JWTClaims{
-		UserID:      userID,
-		PhoneNumber: phoneNumber,
+		UserID:       subject.UserID,
+		PhoneNumber:  subject.PhoneNumber,
+		Role:         subject.Role,
+		Permissions:  subject.Permissions,
+		TokenVersion: subject.TokenVersion,
+		SessionID:    subject.SessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+			Issuer:    issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Subject:   subject.UserID.String(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
 
-	tokenString, err := token.SignedString([]byte(secret))
+	tokenString, err := token.SignedString(key.signKey)
 	if err != nil {
 		return "", err
 	}
@@ -36,13 +355,33 @@ func GenerateJWT(userID uuid.UUID, phoneNumber, secret string) (string, error) {
 	return tokenString, nil
 }
 
-func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
+// ValidateJWT verifies an access token against the keyring. When issuer is
+// not empty the token's iss claim must match it.
+func ValidateJWT(tokenString string, keyring *Keyring, issuer string) (*JWTClaims, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{"HS256", "RS256", "ES256", "EdDSA"})}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		// Tokens issued before kids were introduced carry none and were
+		// signed with what is still the active key
+		key := keyring.Active()
+		if kid, ok := token.Header["kid"].(string); ok {
+			var found bool
+			if key, found = keyring.Lookup(kid); !found {
+				return nil, errors.New("unknown signing key")
+			}
+		}
+
+		// Pin the algorithm to the key so an HMAC token can never be
+		// verified with a public key, or vice versa
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(secret), nil
-	})
+
+		return key.verifyKey, nil
+	}, options...)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generatePEM(t *testing.T, algorithm string) []byte {
+	t.Helper()
+
+	var privateKey interface{}
+	var err error
+
+	switch algorithm {
+	case "RS256":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	}
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestJWTRoundTrip(t *testing.T) {
+	subject := TokenSubject{UserID: uuid.New(), PhoneNumber: "+989123456789", SessionID: uuid.New()}
+
+	keys := map[string]*SigningKey{"HS256": NewHMACSigningKey("default", "secret")}
+	for _, algorithm := range []string{"RS256", "ES256", "EdDSA"} {
+		key, err := ParseSigningKey("", algorithm, generatePEM(t, algorithm))
+		require.NoError(t, err)
+		keys[algorithm] = key
+	}
+
+	for algorithm, key := range keys {
+		t.Run(algorithm, func(t *testing.T) {
+			token, err := GenerateJWT(subject, key, "", time.Minute)
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &JWTClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, key.ID, parsed.Header["kid"])
+			assert.Equal(t, algorithm, parsed.Header["alg"])
+
+			claims, err := ValidateJWT(token, NewKeyring(key), "")
+			require.NoError(t, err)
+			assert.Equal(t, subject.UserID, claims.UserID)
+			assert.Equal(t, subject.SessionID, claims.SessionID)
+			assert.NotEmpty(t, claims.ID)
+		})
+	}
+}
+
+func TestPublicJWK(t *testing.T) {
+	_, ok := NewHMACSigningKey("default", "secret").PublicJWK()
+	assert.False(t, ok, "HMAC keys must never be published")
+
+	expected := map[string]string{"RS256": "RSA", "ES256": "EC", "EdDSA": "OKP"}
+	for algorithm, kty := range expected {
+		key, err := ParseSigningKey("", algorithm, generatePEM(t, algorithm))
+		require.NoError(t, err)
+
+		jwk, ok := key.PublicJWK()
+		assert.True(t, ok)
+		assert.Equal(t, kty, jwk.Kty)
+		assert.Equal(t, algorithm, jwk.Alg)
+		assert.Equal(t, key.ID, jwk.Kid)
+		assert.Len(t, jwk.Kid, 43, "default kid is a base64url SHA-256 thumbprint")
+	}
+}
+
+func TestValidateJWTRejectsOtherKeys(t *testing.T) {
+	subject := TokenSubject{UserID: uuid.New()}
+
+	esKey, err := ParseSigningKey("es", "ES256", generatePEM(t, "ES256"))
+	require.NoError(t, err)
+	otherESKey, err := ParseSigningKey("es", "ES256", generatePEM(t, "ES256"))
+	require.NoError(t, err)
+	hmacKey := NewHMACSigningKey("es", "secret")
+
+	token, err := GenerateJWT(subject, esKey, "", time.Minute)
+	require.NoError(t, err)
+
+	_, err = ValidateJWT(token, NewKeyring(otherESKey), "")
+	assert.Error(t, err, "signature from a different key")
+
+	_, err = ValidateJWT(token, NewKeyring(hmacKey), "")
+	assert.Error(t, err, "algorithm mismatch")
+
+	renamed := *esKey
+	renamed.ID = "other"
+	_, err = ValidateJWT(token, NewKeyring(&renamed), "")
+	assert.Error(t, err, "kid mismatch")
+}
+
+func TestParseSigningKeyRejectsWrongKeyType(t *testing.T) {
+	_, err := ParseSigningKey("", "ES256", generatePEM(t, "RS256"))
+	assert.Error(t, err)
+
+	_, err = ParseSigningKey("", "PS512", generatePEM(t, "RS256"))
+	assert.Error(t, err)
+}
+
+func TestKeyringRotation(t *testing.T) {
+	subject := TokenSubject{UserID: uuid.New()}
+
+	oldKey, err := ParseSigningKey("old", "ES256", generatePEM(t, "ES256"))
+	require.NoError(t, err)
+	newKey, err := ParseSigningKey("new", "ES256", generatePEM(t, "ES256"))
+	require.NoError(t, err)
+
+	keyring := NewKeyring(oldKey)
+	keyring.AddVerificationKey(newKey, time.Time{})
+
+	oldToken, err := GenerateJWT(subject, keyring.Active(), "", time.Minute)
+	require.NoError(t, err)
+
+	jwks := keyring.PublicJWKs()
+	require.Len(t, jwks, 2, "the next key is published before it signs")
+	assert.Equal(t, "old", jwks[0].Kid)
+
+	// Promote the new key and keep the old one verifying for a while
+	rotated := NewKeyring(newKey)
+	rotated.AddVerificationKey(oldKey, time.Now().Add(time.Hour))
+	keyring.Replace(rotated)
+
+	newToken, err := GenerateJWT(subject, keyring.Active(), "", time.Minute)
+	require.NoError(t, err)
+
+	_, err = ValidateJWT(oldToken, keyring, "")
+	assert.NoError(t, err, "tokens signed with the previous key stay valid")
+	_, err = ValidateJWT(newToken, keyring, "")
+	assert.NoError(t, err)
+
+	// Once retired the old key neither verifies nor is published
+	retired := NewKeyring(newKey)
+	retired.AddVerificationKey(oldKey, time.Now().Add(-time.Second))
+	keyring.Replace(retired)
+
+	_, err = ValidateJWT(oldToken, keyring, "")
+	assert.Error(t, err)
+	_, err = ValidateJWT(newToken, keyring, "")
+	assert.NoError(t, err)
+	assert.Len(t, keyring.PublicJWKs(), 1)
+}
+
+func TestKeyringAcceptsTokensWithoutKID(t *testing.T) {
+	key := NewHMACSigningKey("default", "secret")
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{
+		UserID: uuid.New(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	})
+	signed, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = ValidateJWT(signed, NewKeyring(key), "")
+	assert.NoError(t, err)
+}
+
+func TestValidateJWTIssuer(t *testing.T) {
+	key := NewHMACSigningKey("default", "secret")
+	subject := TokenSubject{UserID: uuid.New()}
+
+	token, err := GenerateJWT(subject, key, "https://auth.example.com", time.Minute)
+	require.NoError(t, err)
+
+	claims, err := ValidateJWT(token, NewKeyring(key), "https://auth.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "https://auth.example.com", claims.Issuer)
+
+	_, err = ValidateJWT(token, NewKeyring(key), "https://other.example.com")
+	assert.Error(t, err)
+}
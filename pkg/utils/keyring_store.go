@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KeyringManifestFile lists the keys of a keyring directory
+const KeyringManifestFile = "keyring.json"
+
+const (
+	KeyStatusActive = "active"
+	KeyStatusVerify = "verify"
+)
+
+// KeyringManifest describes the keys in a keyring directory. Exactly one key
+// is active; the others only verify tokens, optionally until RetireAt.
+type KeyringManifest struct {
+	Keys []KeyringManifestEntry `json:"keys"`
+}
+
+type KeyringManifestEntry struct {
+	KID       string     `json:"kid"`
+	Algorithm string     `json:"alg"`
+	File      string     `json:"file"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetireAt  *time.Time `json:"retire_at,omitempty"`
+}
+
+// LoadKeyringManifest reads the manifest of a keyring directory
+func LoadKeyringManifest(dir string) (*KeyringManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, KeyringManifestFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &KeyringManifest{}, nil
+		}
+		return nil, fmt.Errorf("failed to read keyring manifest: %w", err)
+	}
+
+	var manifest KeyringManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// Save writes the manifest atomically so a reloading server never sees a
+// partially written file
+func (m *KeyringManifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode keyring manifest: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, KeyringManifestFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write keyring manifest: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write keyring manifest: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write keyring manifest: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, KeyringManifestFile)); err != nil {
+		return fmt.Errorf("failed to write keyring manifest: %w", err)
+	}
+
+	return nil
+}
+
+// Find returns the manifest entry for kid
+func (m *KeyringManifest) Find(kid string) (*KeyringManifestEntry, bool) {
+	for i := range m.Keys {
+		if m.Keys[i].KID == kid {
+			return &m.Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// Promote makes kid the active signing key. The previously active key keeps
+// verifying tokens until retireAfter has passed.
+func (m *KeyringManifest) Promote(kid string, retireAfter time.Duration) error {
+	entry, ok := m.Find(kid)
+	if !ok {
+		return fmt.Errorf("key %s not found", kid)
+	}
+	if entry.RetireAt != nil && time.Now().After(*entry.RetireAt) {
+		return fmt.Errorf("key %s is retired", kid)
+	}
+
+	retireAt := time.Now().Add(retireAfter).UTC()
+	for i := range m.Keys {
+		if m.Keys[i].Status == KeyStatusActive && m.Keys[i].KID != kid {
+			m.Keys[i].Status = KeyStatusVerify
+			m.Keys[i].RetireAt = &retireAt
+		}
+	}
+
+	entry.Status = KeyStatusActive
+	entry.RetireAt = nil
+	return nil
+}
+
+// Retire schedules a verification key to stop being accepted at the given time
+func (m *KeyringManifest) Retire(kid string, at time.Time) error {
+	entry, ok := m.Find(kid)
+	if !ok {
+		return fmt.Errorf("key %s not found", kid)
+	}
+	if entry.Status == KeyStatusActive {
+		return fmt.Errorf("key %s is active; promote another key first", kid)
+	}
+
+	at = at.UTC()
+	entry.RetireAt = &at
+	return nil
+}
+
+// Prune drops retired keys from the manifest and returns them
+func (m *KeyringManifest) Prune(now time.Time) []KeyringManifestEntry {
+	var kept, pruned []KeyringManifestEntry
+	for _, entry := range m.Keys {
+		if entry.Status != KeyStatusActive && entry.RetireAt != nil && now.After(*entry.RetireAt) {
+			pruned = append(pruned, entry)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	m.Keys = kept
+	return pruned
+}
+
+// GenerateKeyringKey creates a new key file in dir and adds it to the manifest
+// as a verification key, so it is published before it starts signing
+func (m *KeyringManifest) GenerateKeyringKey(dir, algorithm string) (*KeyringManifestEntry, error) {
+	keyData, err := generateKeyMaterial(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	kid := now.Format("20060102") + "-" + strings.ToLower(GenerateRandomString(8))
+	file := kid + ".pem"
+	if algorithm == "HS256" {
+		file = kid + ".secret"
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, file), keyData, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	m.Keys = append(m.Keys, KeyringManifestEntry{
+		KID:       kid,
+		Algorithm: algorithm,
+		File:      file,
+		Status:    KeyStatusVerify,
+		CreatedAt: now,
+	})
+
+	entry := m.Keys[len(m.Keys)-1]
+	return &entry, nil
+}
+
+// LoadKeyring builds a keyring from a keyring directory. Keys whose retirement
+// time has passed are skipped.
+func LoadKeyring(dir string) (*Keyring, error) {
+	manifest, err := LoadKeyringManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyring *Keyring
+	var verifyKeys []KeyringManifestEntry
+	now := time.Now()
+
+	for _, entry := range manifest.Keys {
+		if entry.Status == KeyStatusActive {
+			if keyring != nil {
+				return nil, errors.New("keyring has more than one active key")
+			}
+			key, err := loadManifestKey(dir, entry)
+			if err != nil {
+				return nil, err
+			}
+			keyring = NewKeyring(key)
+			continue
+		}
+
+		if entry.RetireAt == nil || now.Before(*entry.RetireAt) {
+			verifyKeys = append(verifyKeys, entry)
+		}
+	}
+
+	if keyring == nil {
+		return nil, errors.New("keyring has no active key")
+	}
+
+	for _, entry := range verifyKeys {
+		key, err := loadManifestKey(dir, entry)
+		if err != nil {
+			return nil, err
+		}
+
+		var retireAt time.Time
+		if entry.RetireAt != nil {
+			retireAt = *entry.RetireAt
+		}
+		keyring.AddVerificationKey(key, retireAt)
+	}
+
+	return keyring, nil
+}
+
+func loadManifestKey(dir string, entry KeyringManifestEntry) (*SigningKey, error) {
+	path := filepath.Join(dir, entry.File)
+
+	if entry.Algorithm == "HS256" {
+		secret, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %s: %w", entry.KID, err)
+		}
+		return NewHMACSigningKey(entry.KID, strings.TrimSpace(string(secret))), nil
+	}
+
+	return LoadSigningKey(entry.KID, entry.Algorithm, path)
+}
+
+func generateKeyMaterial(algorithm string) ([]byte, error) {
+	var privateKey interface{}
+	var err error
+
+	switch algorithm {
+	case "HS256":
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		return []byte(base64.RawURLEncoding.EncodeToString(secret) + "\n"), nil
+	case "RS256":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 3072)
+	case "ES256":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", algorithm)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s key: %w", algorithm, err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s key: %w", algorithm, err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyringManifestRotation(t *testing.T) {
+	dir := t.TempDir()
+	subject := TokenSubject{UserID: uuid.New()}
+
+	manifest, err := LoadKeyringManifest(dir)
+	require.NoError(t, err)
+
+	first, err := manifest.GenerateKeyringKey(dir, "ES256")
+	require.NoError(t, err)
+	require.NoError(t, manifest.Promote(first.KID, 0))
+	require.NoError(t, manifest.Save(dir))
+
+	keyring, err := LoadKeyring(dir)
+	require.NoError(t, err)
+	assert.Equal(t, first.KID, keyring.Active().ID)
+
+	oldToken, err := GenerateJWT(subject, keyring.Active(), "", time.Minute)
+	require.NoError(t, err)
+
+	second, err := manifest.GenerateKeyringKey(dir, "ES256")
+	require.NoError(t, err)
+	require.NoError(t, manifest.Save(dir))
+
+	keyring, err = LoadKeyring(dir)
+	require.NoError(t, err)
+	assert.Equal(t, first.KID, keyring.Active().ID, "generated keys do not sign until promoted")
+	assert.Len(t, keyring.PublicJWKs(), 2)
+
+	require.NoError(t, manifest.Promote(second.KID, time.Hour))
+	require.NoError(t, manifest.Save(dir))
+
+	keyring, err = LoadKeyring(dir)
+	require.NoError(t, err)
+	assert.Equal(t, second.KID, keyring.Active().ID)
+	_, err = ValidateJWT(oldToken, keyring, "")
+	assert.NoError(t, err)
+
+	assert.Error(t, manifest.Retire(second.KID, time.Now()), "the active key cannot be retired")
+	require.NoError(t, manifest.Retire(first.KID, time.Now().Add(-time.Second)))
+	require.NoError(t, manifest.Save(dir))
+
+	keyring, err = LoadKeyring(dir)
+	require.NoError(t, err)
+	_, err = ValidateJWT(oldToken, keyring, "")
+	assert.Error(t, err)
+
+	pruned := manifest.Prune(time.Now())
+	require.Len(t, pruned, 1)
+	assert.Equal(t, first.KID, pruned[0].KID)
+	assert.Len(t, manifest.Keys, 1)
+}
+
+func TestLoadKeyringHMAC(t *testing.T) {
+	dir := t.TempDir()
+
+	manifest := &KeyringManifest{}
+	entry, err := manifest.GenerateKeyringKey(dir, "HS256")
+	require.NoError(t, err)
+	require.NoError(t, manifest.Promote(entry.KID, 0))
+	require.NoError(t, manifest.Save(dir))
+
+	keyring, err := LoadKeyring(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "HS256", keyring.Active().Method.Alg())
+	assert.Empty(t, keyring.PublicJWKs(), "HMAC keys must never be published")
+}
+
+func TestLoadKeyringRequiresActiveKey(t *testing.T) {
+	dir := t.TempDir()
+
+	manifest := &KeyringManifest{}
+	_, err := manifest.GenerateKeyringKey(dir, "EdDSA")
+	require.NoError(t, err)
+	require.NoError(t, manifest.Save(dir))
+
+	_, err = LoadKeyring(dir)
+	assert.Error(t, err)
+}
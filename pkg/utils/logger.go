@@ -4,7 +4,6 @@ import (
 	"context"
 	"os"
 	"runtime"
-	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -15,9 +14,15 @@ var Logger *logrus.Logger
 func InitLogger() {
 	Logger = logrus.New()
 	Logger.SetOutput(os.Stdout)
+	Logger.AddHook(RedactionHook{})
 
-	logLevel := os.Getenv("LOG_LEVEL")
-	switch logLevel {
+	ConfigureLogger(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+}
+
+// ConfigureLogger applies the configured level and format ("json" or "text").
+// Without a format, JSON is used in gin release mode and text otherwise.
+func ConfigureLogger(level, format string) {
+	switch level {
 	case "debug":
 		Logger.SetLevel(logrus.DebugLevel)
 	case "info":
@@ -30,8 +35,11 @@ func InitLogger() {
 		Logger.SetLevel(logrus.InfoLevel)
 	}
 
-	env := os.Getenv("GIN_MODE")
-	if env == "release" {
+	if format == "" && os.Getenv("GIN_MODE") == "release" {
+		format = "json"
+	}
+
+	if format == "json" {
 		Logger.SetFormatter(&logrus.JSONFormatter{
 			TimestampFormat: time.RFC3339,
 			FieldMap: logrus.FieldMap{
@@ -49,26 +57,37 @@ func InitLogger() {
 	}
 }
 
-func LogWithContext(ctx context.Context) *logrus.Entry {
-	entry := Logger.WithFields(logrus.Fields{})
+type loggerKey struct{}
 
-	if requestID := ctx.Value("request_id"); requestID != nil {
-		entry = entry.WithField("request_id", requestID)
-	}
+// ContextWithLogger returns a copy of ctx carrying entry, so everything
+// logged while serving a request shares its fields
+func ContextWithLogger(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, entry)
+}
+
+// WithLogFields returns a copy of ctx whose logger also carries fields
+func WithLogFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	return ContextWithLogger(ctx, LogWithContext(ctx).WithFields(fields))
+}
 
-	if userID := ctx.Value("user_id"); userID != nil {
-		entry = entry.WithField("user_id", userID)
+// LogWithContext returns the request-scoped logger carried by ctx, falling
+// back to the global Logger outside a request
+func LogWithContext(ctx context.Context) *logrus.Entry {
+	if ctx != nil {
+		if entry, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+			return entry
+		}
 	}
 
-	return entry
+	return logrus.NewEntry(Logger)
 }
 
 func LogWithFields(fields map[string]interface{}) *logrus.Entry {
 	return Logger.WithFields(fields)
 }
 
-func LogRequest(method, path, userAgent, clientIP string, statusCode int, latency time.Duration) {
-	Logger.WithFields(logrus.Fields{
+func LogRequest(ctx context.Context, method, path, userAgent, clientIP string, statusCode int, latency time.Duration) {
+	LogWithContext(ctx).WithFields(logrus.Fields{
 		"method":      method,
 		"path":        path,
 		"user_agent":  userAgent,
@@ -98,18 +117,42 @@ func LogError(err error, message string, fields map[string]interface{}) {
 	Logger.WithFields(fields).Error(message)
 }
 
-func LogOTPGenerated(phoneNumber, code string, expiresAt time.Time) {
-	Logger.WithFields(logrus.Fields{
+// LogOTPGenerated records that a code was issued. The code itself is only
+// included when ConfigureRedaction enabled it for development.
+func LogOTPGenerated(ctx context.Context, phoneNumber, code string, expiresAt time.Time) {
+	fields := logrus.Fields{
 		"phone_number": maskPhoneNumber(phoneNumber),
-		"otp_code":     code,
 		"expires_at":   expiresAt.Format(time.RFC3339),
 		"type":         "otp_generated",
 		"action":       "send_otp",
-	}).Info("OTP Generated")
+	}
+
+	if revealOTPCodes.Load() {
+		fields["otp_code"] = code
+	}
+
+	LogWithContext(ctx).WithFields(fields).Info("OTP Generated")
+}
+
+// LogConsoleSMS stands in for delivering a code when SMS_PROVIDER=console.
+// As with LogOTPGenerated, the code is only included when ConfigureRedaction
+// enabled it for development.
+func LogConsoleSMS(ctx context.Context, phoneNumber, code string) {
+	fields := logrus.Fields{
+		"phone_number": maskPhoneNumber(phoneNumber),
+		"type":         "sms_console",
+		"action":       "send_otp",
+	}
+
+	if revealOTPCodes.Load() {
+		fields["otp_code"] = code
+	}
+
+	LogWithContext(ctx).WithFields(fields).Info("SMS Written To Console")
 }
 
-func LogOTPVerification(phoneNumber, code string, success bool, reason string) {
-	Logger.WithFields(logrus.Fields{
+func LogOTPVerification(ctx context.Context, phoneNumber string, success bool, reason string) {
+	LogWithContext(ctx).WithFields(logrus.Fields{
 		"phone_number": maskPhoneNumber(phoneNumber),
 		"success":      success,
 		"reason":       reason,
@@ -118,8 +161,26 @@ func LogOTPVerification(phoneNumber, code string, success bool, reason string) {
 	}).Info("OTP Verification Attempt")
 }
 
-func LogUserRegistration(userID, phoneNumber string) {
-	Logger.WithFields(logrus.Fields{
+func LogOTPDelivery(ctx context.Context, phoneNumber, provider string, success bool, errorMsg string) {
+	fields := logrus.Fields{
+		"phone_number": maskPhoneNumber(phoneNumber),
+		"provider":     provider,
+		"success":      success,
+		"type":         "otp_delivery",
+		"action":       "send_otp",
+	}
+
+	if !success {
+		fields["error"] = errorMsg
+		LogWithContext(ctx).WithFields(fields).Error("OTP Delivery Failed")
+		return
+	}
+
+	LogWithContext(ctx).WithFields(fields).Info("OTP Delivered")
+}
+
+func LogUserRegistration(ctx context.Context, userID, phoneNumber string) {
+	LogWithContext(ctx).WithFields(logrus.Fields{
 		"user_id":      userID,
 		"phone_number": maskPhoneNumber(phoneNumber),
 		"type":         "user_registration",
@@ -127,8 +188,8 @@ func LogUserRegistration(userID, phoneNumber string) {
 	}).Info("New User Registered")
 }
 
-func LogUserLogin(userID, phoneNumber string) {
-	Logger.WithFields(logrus.Fields{
+func LogUserLogin(ctx context.Context, userID, phoneNumber string) {
+	LogWithContext(ctx).WithFields(logrus.Fields{
 		"user_id":      userID,
 		"phone_number": maskPhoneNumber(phoneNumber),
 		"type":         "user_login",
@@ -136,19 +197,25 @@ func LogUserLogin(userID, phoneNumber string) {
 	}).Info("User Login")
 }
 
-func LogSecurityEvent(eventType, userID, phoneNumber, details string) {
-	Logger.WithFields(logrus.Fields{
+func LogSecurityEvent(ctx context.Context, eventType, userID, phoneNumber, details string) {
+	fields := logrus.Fields{
 		"event_type":   eventType,
-		"user_id":      userID,
 		"phone_number": maskPhoneNumber(phoneNumber),
 		"details":      details,
 		"type":         "security",
 		"severity":     "warning",
-	}).Warn("Security Event")
+	}
+
+	// Keep the request's user_id unless the event concerns a specific user
+	if userID != "" {
+		fields["user_id"] = userID
+	}
+
+	LogWithContext(ctx).WithFields(fields).Warn("Security Event")
 }
 
-func LogRateLimit(phoneNumber string, attempts, maxAttempts int) {
-	Logger.WithFields(logrus.Fields{
+func LogRateLimit(ctx context.Context, phoneNumber string, attempts, maxAttempts int) {
+	LogWithContext(ctx).WithFields(logrus.Fields{
 		"phone_number": maskPhoneNumber(phoneNumber),
 		"attempts":     attempts,
 		"max_attempts": maxAttempts,
@@ -157,7 +224,7 @@ func LogRateLimit(phoneNumber string, attempts, maxAttempts int) {
 	}).Warn("Rate Limit Exceeded")
 }
 
-func LogDatabaseOperation(operation, table string, success bool, errorMsg string) {
+func LogDatabaseOperation(ctx context.Context, operation, table string, success bool, errorMsg string) {
 	fields := logrus.Fields{
 		"operation": operation,
 		"table":     table,
@@ -167,16 +234,8 @@ func LogDatabaseOperation(operation, table string, success bool, errorMsg string
 
 	if !success && errorMsg != "" {
 		fields["error"] = errorMsg
-		Logger.WithFields(fields).Error("Database Operation Failed")
+		LogWithContext(ctx).WithFields(fields).Error("Database Operation Failed")
 	} else {
-		Logger.WithFields(fields).Debug("Database Operation")
+		LogWithContext(ctx).WithFields(fields).Debug("Database Operation")
 	}
 }
-
-func maskPhoneNumber(phoneNumber string) string {
-	if len(phoneNumber) <= 4 {
-		return strings.Repeat("*", len(phoneNumber))
-	}
-
-	return phoneNumber[:2] + strings.Repeat("*", len(phoneNumber)-4) + phoneNumber[len(phoneNumber)-2:]
-}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs swaps the global Logger for one that records entries
+func captureLogs(t *testing.T) *test.Hook {
+	t.Helper()
+
+	previous := Logger
+	Logger = logrus.New()
+	Logger.SetOutput(io.Discard)
+	Logger.SetLevel(logrus.DebugLevel)
+	t.Cleanup(func() { Logger = previous })
+
+	return test.NewLocal(Logger)
+}
+
+func TestLogWithContextCarriesRequestFields(t *testing.T) {
+	hook := captureLogs(t)
+
+	ctx := WithLogFields(context.Background(), map[string]interface{}{"request_id": "req-1"})
+	ctx = WithLogFields(ctx, map[string]interface{}{"user_id": "user-1"})
+
+	LogDatabaseOperation(ctx, "find", "users", false, "boom")
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "req-1", entry.Data["request_id"])
+	assert.Equal(t, "user-1", entry.Data["user_id"])
+	assert.Equal(t, "users", entry.Data["table"])
+}
+
+func TestLogWithContextFallsBackToGlobalLogger(t *testing.T) {
+	hook := captureLogs(t)
+
+	LogWithContext(context.Background()).Info("no request")
+
+	require.Len(t, hook.Entries, 1)
+	assert.Empty(t, hook.LastEntry().Data)
+}
+
+func TestLogSecurityEventKeepsRequestUser(t *testing.T) {
+	hook := captureLogs(t)
+	ctx := WithLogFields(context.Background(), map[string]interface{}{"user_id": "admin"})
+
+	LogSecurityEvent(ctx, "access_denied", "", "", "GET /users")
+	assert.Equal(t, "admin", hook.LastEntry().Data["user_id"])
+
+	LogSecurityEvent(ctx, "role_changed", "target", "", "role changed")
+	assert.Equal(t, "target", hook.LastEntry().Data["user_id"])
+}
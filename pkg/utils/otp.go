@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 )
@@ -19,6 +22,25 @@ func GenerateOTP() (string, error) {
 	return fmt.Sprintf("%06d", otp), nil
 }
 
+// HashOTP returns the keyed HMAC-SHA256 of an OTP code, hex encoded
+func HashOTP(code, pepper string) string {
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyOTPHash reports whether code matches hash, in constant time
+func VerifyOTPHash(code, hash, pepper string) bool {
+	expected, err := hex.DecodeString(hash)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte(code))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
 func GenerateRandomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	result := make([]byte, length)
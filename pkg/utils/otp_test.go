@@ -18,6 +18,26 @@ func TestGenerateOTP(t *testing.T) {
 	}
 }
 
+func TestHashOTP(t *testing.T) {
+	hash := HashOTP("123456", "pepper")
+
+	assert.Len(t, hash, 64)
+	assert.NotContains(t, hash, "123456")
+	assert.Equal(t, hash, HashOTP("123456", "pepper"))
+	assert.NotEqual(t, hash, HashOTP("123456", "other-pepper"))
+	assert.NotEqual(t, hash, HashOTP("654321", "pepper"))
+}
+
+func TestVerifyOTPHash(t *testing.T) {
+	hash := HashOTP("123456", "pepper")
+
+	assert.True(t, VerifyOTPHash("123456", hash, "pepper"))
+	assert.False(t, VerifyOTPHash("123457", hash, "pepper"))
+	assert.False(t, VerifyOTPHash("123456", hash, "other-pepper"))
+	assert.False(t, VerifyOTPHash("123456", "not-hex", "pepper"))
+	assert.False(t, VerifyOTPHash("123456", "", "pepper"))
+}
+
 func TestGenerateRandomString(t *testing.T) {
 	tests := []int{5, 10, 16, 32}
 
@@ -0,0 +1,41 @@
+package utils
+
+import "strings"
+
+// twoDigitCallingCodes are the ITU country calling codes of two digits. Apart
+// from 1 (North America) and 7 (Russia, Kazakhstan), every other code has
+// three digits; no code is a prefix of another.
+var twoDigitCallingCodes = map[string]bool{
+	"20": true, "27": true, "30": true, "31": true, "32": true, "33": true,
+	"34": true, "36": true, "39": true, "40": true, "41": true, "43": true,
+	"44": true, "45": true, "46": true, "47": true, "48": true, "49": true,
+	"51": true, "52": true, "53": true, "54": true, "55": true, "56": true,
+	"57": true, "58": true, "60": true, "61": true, "62": true, "63": true,
+	"64": true, "65": true, "66": true, "81": true, "82": true, "84": true,
+	"86": true, "90": true, "91": true, "92": true, "93": true, "94": true,
+	"95": true, "98": true,
+}
+
+// NormalizePhoneNumber returns the number in E.164 form, with a leading +
+func NormalizePhoneNumber(phoneNumber string) string {
+	return "+" + strings.TrimPrefix(strings.TrimSpace(phoneNumber), "+")
+}
+
+// CountryCallingCode returns the calling code of an E.164 number with its +,
+// e.g. "+44" for "+447700900123"
+func CountryCallingCode(phoneNumber string) string {
+	digits := strings.TrimPrefix(NormalizePhoneNumber(phoneNumber), "+")
+
+	switch {
+	case digits == "":
+		return ""
+	case digits[0] == '1' || digits[0] == '7':
+		return "+" + digits[:1]
+	case len(digits) >= 2 && twoDigitCallingCodes[digits[:2]]:
+		return "+" + digits[:2]
+	case len(digits) >= 3:
+		return "+" + digits[:3]
+	default:
+		return "+" + digits
+	}
+}
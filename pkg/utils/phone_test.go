@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountryCallingCode(t *testing.T) {
+	tests := []struct {
+		phoneNumber string
+		expected    string
+	}{
+		{"+15551234567", "+1"},
+		{"+18765551234", "+1"},
+		{"+79161234567", "+7"},
+		{"+447700900123", "+44"},
+		{"+989123456789", "+98"},
+		{"+2348012345678", "+234"},
+		{"+37060012345", "+370"},
+		{"+882123456789", "+882"},
+		{"447700900123", "+44"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, CountryCallingCode(tt.phoneNumber), tt.phoneNumber)
+	}
+}
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	assert.Equal(t, "+447700900123", NormalizePhoneNumber("447700900123"))
+	assert.Equal(t, "+447700900123", NormalizePhoneNumber("+447700900123"))
+}
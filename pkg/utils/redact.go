@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// secretFields are log fields whose values are never written
+var secretFields = map[string]bool{
+	"otp_code":           true,
+	"code":               true,
+	"code_hash":          true,
+	"password":           true,
+	"secret":             true,
+	"pepper":             true,
+	"token":              true,
+	"access_token":       true,
+	"refresh_token":      true,
+	"refresh_token_hash": true,
+	"authorization":      true,
+	"api_key":            true,
+}
+
+// phoneFields are log fields holding a phone number, which are masked
+var phoneFields = map[string]bool{
+	"phone_number": true,
+	"phone":        true,
+}
+
+// Secrets that can turn up inside free text, such as error messages
+var (
+	jwtPattern          = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bearerPattern       = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/=-]+`)
+	refreshTokenPattern = regexp.MustCompile(`[0-9a-fA-F-]{36}\.[A-Za-z0-9_-]{43}`)
+	phonePattern        = regexp.MustCompile(`\+[1-9][0-9]{8,14}`)
+)
+
+// revealOTPCodes lets LogOTPGenerated write the code; development only
+var revealOTPCodes atomic.Bool
+
+// ConfigureRedaction sets whether generated OTP codes are logged. Every other
+// secret stays redacted regardless.
+func ConfigureRedaction(revealCodes bool) {
+	revealOTPCodes.Store(revealCodes)
+}
+
+// RedactionHook strips secrets and masks phone numbers in every entry before
+// it is formatted. InitLogger installs it ahead of any other hook, so later
+// hooks only ever see the redacted entry.
+type RedactionHook struct{}
+
+func (RedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (RedactionHook) Fire(entry *logrus.Entry) error {
+	for key, value := range entry.Data {
+		entry.Data[key] = redactField(key, value)
+	}
+	entry.Message = RedactString(entry.Message)
+	return nil
+}
+
+func redactField(key string, value interface{}) interface{} {
+	name := strings.ToLower(key)
+
+	switch {
+	case name == "otp_code" && revealOTPCodes.Load():
+		return value
+	case secretFields[name]:
+		return redacted
+	case phoneFields[name]:
+		return maskPhoneNumber(fmt.Sprint(value))
+	}
+
+	switch v := value.(type) {
+	case string:
+		return RedactString(v)
+	case error:
+		return RedactString(v.Error())
+	default:
+		return value
+	}
+}
+
+// RedactString removes tokens from free text and masks phone numbers in it
+func RedactString(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	s = refreshTokenPattern.ReplaceAllString(s, redacted)
+	return phonePattern.ReplaceAllStringFunc(s, maskPhoneNumber)
+}
+
+// maskPhoneNumber keeps the first and last two characters. Masking an already
+// masked number leaves it unchanged.
+func maskPhoneNumber(phoneNumber string) string {
+	if len(phoneNumber) <= 4 {
+		return strings.Repeat("*", len(phoneNumber))
+	}
+
+	return phoneNumber[:2] + strings.Repeat("*", len(phoneNumber)-4) + phoneNumber[len(phoneNumber)-2:]
+}
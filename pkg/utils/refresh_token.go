@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const refreshTokenSecretBytes = 32
+
+// GenerateRefreshToken returns an opaque refresh token bound to a session.
+// The token is "<session id>.<random secret>"; only its hash is stored.
+func GenerateRefreshToken(sessionID uuid.UUID) (string, error) {
+	secret := make([]byte, refreshTokenSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	return sessionID.String() + "." + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// ParseRefreshToken extracts the session ID from a refresh token
+func ParseRefreshToken(token string) (uuid.UUID, error) {
+	sessionPart, secretPart, found := strings.Cut(token, ".")
+	if !found || secretPart == "" {
+		return uuid.Nil, errors.New("malformed refresh token")
+	}
+
+	sessionID, err := uuid.Parse(sessionPart)
+	if err != nil {
+		return uuid.Nil, errors.New("malformed refresh token")
+	}
+
+	return sessionID, nil
+}
+
+// HashRefreshToken returns the SHA-256 of a refresh token, hex encoded
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshTokenMatches compares a refresh token with a stored hash in constant time
+func RefreshTokenMatches(token, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashRefreshToken(token)), []byte(hash)) == 1
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshTokenRoundTrip(t *testing.T) {
+	sessionID := uuid.New()
+
+	token, err := GenerateRefreshToken(sessionID)
+	require.NoError(t, err)
+
+	parsed, err := ParseRefreshToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, sessionID, parsed)
+
+	other, err := GenerateRefreshToken(sessionID)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	hash := HashRefreshToken(token)
+	assert.True(t, RefreshTokenMatches(token, hash))
+	assert.False(t, RefreshTokenMatches(other, hash))
+}
+
+func TestParseRefreshTokenRejectsMalformed(t *testing.T) {
+	for _, token := range []string{"", "abc", "not-a-uuid.secret", uuid.NewString() + ".", uuid.NewString()} {
+		_, err := ParseRefreshToken(token)
+		assert.Error(t, err, "token %q", token)
+	}
+}